@@ -0,0 +1,181 @@
+package alerts
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tbuckley/go-issuetracker/analytics"
+	"github.com/tbuckley/go-issuetracker/common"
+)
+
+// Rule fires when Metric compared by Op ("<", "<=", ">", ">=") with Threshold
+// holds for For consecutive evaluations. If ChangeOver is set the rule
+// compares the percent change of Metric over that period instead, e.g.
+// "untriaged grew by 20% week over week". A firing rule resolves only once the
+// value is back on the other side of Clear, which defaults to Threshold.
+type Rule struct {
+	Name       string          `json:"name"`
+	Metric     string          `json:"metric"`
+	Op         string          `json:"op"`
+	Threshold  float64         `json:"threshold"`
+	Clear      *float64        `json:"clear"`
+	ChangeOver common.Duration `json:"changeOver"`
+	For        int             `json:"for"`
+}
+
+func compare(op string, value, threshold float64) bool {
+	switch op {
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	case ">=":
+		return value >= threshold
+	default:
+		return value > threshold
+	}
+}
+
+func (r *Rule) clearThreshold() float64 {
+	if r.Clear != nil {
+		return *r.Clear
+	}
+	return r.Threshold
+}
+
+func (r *Rule) Describe() string {
+	if r.ChangeOver > 0 {
+		return fmt.Sprintf("%v change over %v %v %v%%", r.Metric, time.Duration(r.ChangeOver), r.Op, r.Threshold)
+	}
+	return fmt.Sprintf("%v %v %v", r.Metric, r.Op, r.Threshold)
+}
+
+type Sample struct {
+	Time    time.Time         `json:"time"`
+	Metrics analytics.Metrics `json:"metrics"`
+}
+
+type AlertState struct {
+	Firing  bool      `json:"firing"`
+	Pending int       `json:"pending"`
+	Since   time.Time `json:"since"`
+}
+
+// State is carried between evaluations and must be persisted by the caller.
+type State struct {
+	Alerts  map[string]*AlertState `json:"alerts"`
+	History []*Sample              `json:"history"`
+}
+
+func NewState() *State {
+	return &State{Alerts: make(map[string]*AlertState)}
+}
+
+type Event struct {
+	Rule   string    `json:"rule"`
+	Detail string    `json:"detail"`
+	Firing bool      `json:"firing"`
+	Value  float64   `json:"value"`
+	Time   time.Time `json:"time"`
+}
+
+func (e *Event) String() string {
+	status := "RESOLVED"
+	if e.Firing {
+		status = "FIRING"
+	}
+	return fmt.Sprintf("[%v] %v: %v (value %v)", status, e.Rule, e.Detail, e.Value)
+}
+
+// valueAt returns the metric from the latest sample taken at or before t.
+func (s *State) valueAt(metric string, t time.Time) (float64, bool) {
+	for i := len(s.History) - 1; i >= 0; i-- {
+		sample := s.History[i]
+		if !sample.Time.After(t) {
+			value, ok := sample.Metrics[metric]
+			return value, ok
+		}
+	}
+	return 0, false
+}
+
+func (r *Rule) value(state *State, now time.Time, metrics analytics.Metrics) (float64, bool) {
+	current, ok := metrics[r.Metric]
+	if !ok || r.ChangeOver <= 0 {
+		return current, ok
+	}
+	previous, ok := state.valueAt(r.Metric, now.Add(-time.Duration(r.ChangeOver)))
+	if !ok || previous == 0 {
+		return 0, false
+	}
+	return (current - previous) / previous * 100, true
+}
+
+// Evaluate checks every rule against metrics, updates state and returns the
+// alerts that started firing or resolved. A firing alert whose metric has no
+// value resolves with a detail saying so.
+func Evaluate(rules []*Rule, state *State, now time.Time, metrics analytics.Metrics) []*Event {
+	if state.Alerts == nil {
+		state.Alerts = make(map[string]*AlertState)
+	}
+
+	events := make([]*Event, 0)
+	var keep time.Duration
+	for _, rule := range rules {
+		if time.Duration(rule.ChangeOver) > keep {
+			keep = time.Duration(rule.ChangeOver)
+		}
+
+		alert, ok := state.Alerts[rule.Name]
+		if !ok {
+			alert = new(AlertState)
+			state.Alerts[rule.Name] = alert
+		}
+		value, ok := rule.value(state, now, metrics)
+		if !ok {
+			// Without a value the alert can't be checked, so don't leave
+			// it firing on stale data
+			alert.Pending = 0
+			if alert.Firing {
+				alert.Firing = false
+				alert.Since = now
+				events = append(events, &Event{Rule: rule.Name, Detail: fmt.Sprintf("%v: no value for %v", rule.Describe(), rule.Metric), Time: now})
+			}
+			continue
+		}
+
+		if alert.Firing {
+			// Stay firing until the value moves past the clear threshold
+			if !compare(rule.Op, value, rule.clearThreshold()) && value != rule.clearThreshold() {
+				alert.Firing = false
+				alert.Pending = 0
+				alert.Since = now
+				events = append(events, &Event{Rule: rule.Name, Detail: rule.Describe(), Value: value, Time: now})
+			}
+			continue
+		}
+
+		if compare(rule.Op, value, rule.Threshold) {
+			alert.Pending++
+			if alert.Pending >= rule.For {
+				alert.Firing = true
+				alert.Since = now
+				events = append(events, &Event{Rule: rule.Name, Detail: rule.Describe(), Firing: true, Value: value, Time: now})
+			}
+		} else {
+			alert.Pending = 0
+		}
+	}
+
+	state.History = append(state.History, &Sample{Time: now, Metrics: metrics})
+	sort.SliceStable(state.History, func(i, j int) bool {
+		return state.History[i].Time.Before(state.History[j].Time)
+	})
+	// Keep one sample older than the longest comparison period
+	cutoff := now.Add(-keep)
+	for len(state.History) > 1 && !state.History[1].Time.After(cutoff) {
+		state.History = state.History[1:]
+	}
+	return events
+}
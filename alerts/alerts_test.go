@@ -0,0 +1,86 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tbuckley/go-issuetracker/analytics"
+	"github.com/tbuckley/go-issuetracker/common"
+)
+
+var start = time.Date(2015, 3, 2, 0, 0, 0, 0, time.UTC)
+
+func TestEvaluateHysteresis(t *testing.T) {
+	clear := 3.0
+	rules := []*Rule{{Name: "p1", Metric: "p1", Op: ">", Threshold: 5, Clear: &clear}}
+	state := NewState()
+
+	steps := []struct {
+		value  float64
+		events int
+		firing bool
+	}{
+		{4, 0, false},
+		{6, 1, true},
+		// Between the thresholds the alert keeps firing
+		{4, 0, true},
+		{3, 0, true},
+		{2, 1, false},
+		{4, 0, false},
+	}
+	for i, step := range steps {
+		now := start.Add(time.Duration(i) * time.Hour)
+		events := Evaluate(rules, state, now, analytics.Metrics{"p1": step.value})
+		if len(events) != step.events {
+			t.Errorf("step %v: got %v events, want %v", i, len(events), step.events)
+		}
+		if state.Alerts["p1"].Firing != step.firing {
+			t.Errorf("step %v: firing is %v, want %v", i, state.Alerts["p1"].Firing, step.firing)
+		}
+	}
+}
+
+func TestEvaluateFor(t *testing.T) {
+	rules := []*Rule{{Name: "no-owner", Metric: "no_owner", Op: ">", Threshold: 50, For: 2}}
+	state := NewState()
+
+	if events := Evaluate(rules, state, start, analytics.Metrics{"no_owner": 60}); len(events) != 0 {
+		t.Errorf("fired after one evaluation: %v", events)
+	}
+	events := Evaluate(rules, state, start.Add(time.Hour), analytics.Metrics{"no_owner": 60})
+	if len(events) != 1 || !events[0].Firing {
+		t.Errorf("got %v, want the alert to fire", events)
+	}
+}
+
+func TestEvaluateChangeOver(t *testing.T) {
+	week := 7 * 24 * time.Hour
+	rules := []*Rule{{Name: "growth", Metric: "untriaged", Op: ">", Threshold: 20, ChangeOver: common.Duration(week)}}
+	state := NewState()
+
+	Evaluate(rules, state, start, analytics.Metrics{"untriaged": 100})
+	if events := Evaluate(rules, state, start.Add(week), analytics.Metrics{"untriaged": 110}); len(events) != 0 {
+		t.Errorf("fired on 10%% growth: %v", events)
+	}
+	events := Evaluate(rules, state, start.Add(2*week), analytics.Metrics{"untriaged": 140})
+	if len(events) != 1 || !events[0].Firing || events[0].Value < 27 || events[0].Value > 28 {
+		t.Errorf("got %v, want the alert to fire on 27%% growth", events)
+	}
+}
+
+func TestEvaluateMissingMetricResolves(t *testing.T) {
+	rules := []*Rule{{Name: "p1", Metric: "p1", Op: ">", Threshold: 5}}
+	state := NewState()
+
+	Evaluate(rules, state, start, analytics.Metrics{"p1": 6})
+	if !state.Alerts["p1"].Firing {
+		t.Fatalf("alert didn't fire")
+	}
+	events := Evaluate(rules, state, start.Add(time.Hour), analytics.Metrics{})
+	if len(events) != 1 || events[0].Firing {
+		t.Errorf("got %v, want the alert to resolve", events)
+	}
+	if state.Alerts["p1"].Firing {
+		t.Errorf("alert is still firing without a value")
+	}
+}
@@ -0,0 +1,59 @@
+package alerts
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/smtp"
+)
+
+type EmailConfig struct {
+	Addr     string   `json:"addr"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	Subject  string   `json:"subject"`
+}
+
+func (c *EmailConfig) Email() *Email {
+	email := &Email{
+		Addr:    c.Addr,
+		From:    c.From,
+		To:      c.To,
+		Subject: c.Subject,
+	}
+	if c.Username != "" {
+		host, _, err := net.SplitHostPort(c.Addr)
+		if err != nil {
+			host = c.Addr
+		}
+		email.Auth = smtp.PlainAuth("", c.Username, c.Password, host)
+	}
+	return email
+}
+
+type Config struct {
+	Rules    []*Rule      `json:"rules"`
+	Webhooks []string     `json:"webhooks"`
+	Email    *EmailConfig `json:"email"`
+}
+
+func ParseConfig(data []byte) (*Config, error) {
+	config := new(Config)
+	err := json.Unmarshal(data, config)
+	return config, err
+}
+
+// Notifiers builds the configured notifiers. Webhooks use client, which may
+// be nil for http.DefaultClient.
+func (c *Config) Notifiers(client *http.Client) []Notifier {
+	notifiers := make([]Notifier, 0)
+	for _, url := range c.Webhooks {
+		notifiers = append(notifiers, &Webhook{URL: url, Client: client})
+	}
+	if c.Email != nil {
+		notifiers = append(notifiers, c.Email.Email())
+	}
+	return notifiers
+}
@@ -0,0 +1,130 @@
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+type Notifier interface {
+	Notify(events []*Event) error
+}
+
+type WebhookError struct {
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+func (e *WebhookError) Error() string {
+	return fmt.Sprintf("webhook %v returned %v", e.URL, e.StatusCode)
+}
+
+// Webhook posts events as JSON: {"events": [...]}.
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+func (w *Webhook) Notify(events []*Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(map[string]interface{}{"events": events})
+	if err != nil {
+		return err
+	}
+
+	client := http.DefaultClient
+	if w.Client != nil {
+		client = w.Client
+	}
+	resp, err := client.Post(w.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := ioutil.ReadAll(resp.Body)
+		return &WebhookError{URL: w.URL, StatusCode: resp.StatusCode, Body: string(data)}
+	}
+	return nil
+}
+
+// Email sends all events of an evaluation as a single digest message through
+// the SMTP server at Addr (host:port). Auth may be nil.
+type Email struct {
+	Addr    string
+	Auth    smtp.Auth
+	From    string
+	To      []string
+	Subject string
+}
+
+// Digest returns the subject and plain text body of the message for events.
+func (e *Email) Digest(events []*Event) (string, string) {
+	subject := e.Subject
+	if subject == "" {
+		subject = "Issue tracker alerts"
+	}
+	firing := 0
+	for _, event := range events {
+		if event.Firing {
+			firing++
+		}
+	}
+
+	body := new(bytes.Buffer)
+	for _, event := range events {
+		fmt.Fprintf(body, "%v\r\n", event.String())
+	}
+	return fmt.Sprintf("%v (%v firing, %v resolved)", subject, firing, len(events)-firing), body.String()
+}
+
+func (e *Email) Message(events []*Event) []byte {
+	subject, body := e.Digest(events)
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "From: %v\r\n", e.From)
+	fmt.Fprintf(buf, "To: %v\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(buf, "Subject: %v\r\n", subject)
+	fmt.Fprintf(buf, "Date: %v\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(buf, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	buf.WriteString(body)
+	return buf.Bytes()
+}
+
+func (e *Email) Notify(events []*Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return smtp.SendMail(e.Addr, e.Auth, e.From, e.To, e.Message(events))
+}
+
+type MultiError []error
+
+func (m MultiError) Error() string {
+	messages := make([]string, len(m))
+	for i, err := range m {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// NotifyAll delivers events to every notifier, returning all failures.
+func NotifyAll(notifiers []Notifier, events []*Event) error {
+	errs := make(MultiError, 0)
+	for _, notifier := range notifiers {
+		if err := notifier.Notify(events); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package alerts
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var events = []*Event{
+	{Rule: "too-many-p1", Detail: "p1 > 5", Firing: true, Value: 6, Time: start},
+	{Rule: "no-owner", Detail: "no_owner > 50", Value: 40, Time: start},
+}
+
+func TestWebhook(t *testing.T) {
+	var received struct {
+		Events []*Event `json:"events"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got content type %v", r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("decoding body: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	if err := (&Webhook{URL: server.URL}).Notify(events); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if len(received.Events) != 2 || received.Events[0].Rule != "too-many-p1" || !received.Events[0].Firing {
+		t.Errorf("got events %+v", received.Events)
+	}
+}
+
+func TestWebhookError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	err := NotifyAll([]Notifier{&Webhook{URL: server.URL}}, events)
+	errs, ok := err.(MultiError)
+	if !ok || len(errs) != 1 {
+		t.Fatalf("got error %v, want one failure", err)
+	}
+	if webhookErr, ok := errs[0].(*WebhookError); !ok || webhookErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("got %v, want a WebhookError", errs[0])
+	}
+}
+
+type smtpMessage struct {
+	From string
+	To   []string
+	Data string
+}
+
+// fakeSMTP accepts a single message and sends its envelope and data on the
+// returned channel.
+func fakeSMTP(t *testing.T) (string, chan *smtpMessage) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := make(chan *smtpMessage, 1)
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		message := new(smtpMessage)
+		reply("220 localhost")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			command := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "MAIL FROM:"):
+				message.From = strings.Trim(line[len("MAIL FROM:"):], "<> ")
+				reply("250 OK")
+			case strings.HasPrefix(command, "RCPT TO:"):
+				message.To = append(message.To, strings.Trim(line[len("RCPT TO:"):], "<> "))
+				reply("250 OK")
+			case command == "DATA":
+				reply("354 Go ahead")
+				data := new(strings.Builder)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				message.Data = data.String()
+				messages <- message
+				reply("250 OK")
+			case command == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("502 Unsupported")
+			}
+		}
+	}()
+	return listener.Addr().String(), messages
+}
+
+func TestEmail(t *testing.T) {
+	addr, messages := fakeSMTP(t)
+	config := &Config{Email: &EmailConfig{
+		Addr:    addr,
+		From:    "alerts@example.com",
+		To:      []string{"triage@example.com"},
+		Subject: "UI alerts",
+	}}
+
+	if err := NotifyAll(config.Notifiers(nil), events); err != nil {
+		t.Fatalf("NotifyAll: %v", err)
+	}
+	message := <-messages
+	if message.From != "alerts@example.com" || len(message.To) != 1 || message.To[0] != "triage@example.com" {
+		t.Errorf("got envelope from %v to %v", message.From, message.To)
+	}
+	for _, want := range []string{
+		"Subject: UI alerts (1 firing, 1 resolved)",
+		"[FIRING] too-many-p1: p1 > 5 (value 6)",
+		"[RESOLVED] no-owner: no_owner > 50 (value 40)",
+	} {
+		if !strings.Contains(message.Data, want) {
+			t.Errorf("message is missing %q:\n%v", want, message.Data)
+		}
+	}
+}
+
+func TestEmailNoEvents(t *testing.T) {
+	// Nothing listens here, so sending anything would fail
+	email := &Email{Addr: "127.0.0.1:1", From: "alerts@example.com", To: []string{"triage@example.com"}}
+	if err := email.Notify(nil); err != nil {
+		t.Errorf("Notify with no events: %v", err)
+	}
+}
@@ -0,0 +1,319 @@
+package analytics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tbuckley/go-issuetracker/common"
+	"github.com/tbuckley/go-issuetracker/gcode"
+)
+
+type Metric int
+
+const (
+	TimeToTriage Metric = iota
+	TimeToOwner
+	TimeToClose
+)
+
+func (m Metric) String() string {
+	switch m {
+	case TimeToTriage:
+		return "triage"
+	case TimeToOwner:
+		return "owner"
+	case TimeToClose:
+		return "close"
+	}
+	return "unknown"
+}
+
+// Elapsed is the time from Published until an event. When Done is false the
+// event hasn't happened yet and Duration is the issue's age so far.
+type Elapsed struct {
+	Duration time.Duration
+	Done     bool
+}
+
+type IssueTimings struct {
+	Issue  *gcode.Issue
+	Triage Elapsed
+	Owner  Elapsed
+	Close  Elapsed
+}
+
+func (t *IssueTimings) Get(metric Metric) Elapsed {
+	switch metric {
+	case TimeToTriage:
+		return t.Triage
+	case TimeToOwner:
+		return t.Owner
+	default:
+		return t.Close
+	}
+}
+
+func parseTime(value string) (time.Time, bool) {
+	parsed, err := time.Parse("2006-01-02T15:04:05.000Z", value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return parsed, true
+}
+
+// GetIssueTimings derives elapsed times from the issue's replies. An issue
+// with no status or owner changes is treated as having been filed with its
+// current status and owner.
+func GetIssueTimings(issue *gcode.Issue, now time.Time) (*IssueTimings, bool) {
+	published, ok := common.GetIssuePublished(issue)
+	if !ok {
+		return nil, false
+	}
+	age := Elapsed{Duration: now.Sub(published)}
+	timings := &IssueTimings{Issue: issue, Triage: age, Owner: age, Close: age}
+
+	statusChanged, ownerChanged := false, false
+	for _, reply := range issue.Replies {
+		at, ok := parseTime(reply.Published)
+		if !ok {
+			continue
+		}
+		if reply.StatusChange != "" {
+			statusChanged = true
+			if !timings.Triage.Done && reply.StatusChange != "Untriaged" {
+				timings.Triage = Elapsed{Duration: at.Sub(published), Done: true}
+			}
+		}
+		if reply.OwnerChange != "" {
+			ownerChanged = true
+			if !timings.Owner.Done && reply.OwnerChange != "----" {
+				timings.Owner = Elapsed{Duration: at.Sub(published), Done: true}
+			}
+		}
+	}
+	if !statusChanged && issue.Status != "" && issue.Status != "Untriaged" {
+		timings.Triage = Elapsed{Done: true}
+	}
+	if !ownerChanged && issue.Owner != "" {
+		timings.Owner = Elapsed{Done: true}
+	}
+	if closed, ok := common.GetIssueClosed(issue); ok {
+		timings.Close = Elapsed{Duration: closed.Sub(published), Done: true}
+	}
+	return timings, true
+}
+
+func GetAllIssueTimings(issues []*gcode.Issue, now time.Time) []*IssueTimings {
+	all := make([]*IssueTimings, 0, len(issues))
+	for _, issue := range issues {
+		if timings, ok := GetIssueTimings(issue, now); ok {
+			all = append(all, timings)
+		}
+	}
+	return all
+}
+
+type ElapsedStats struct {
+	Count   int           `json:"count"`
+	Pending int           `json:"pending"`
+	Mean    time.Duration `json:"mean"`
+	P50     time.Duration `json:"p50"`
+	P90     time.Duration `json:"p90"`
+	P99     time.Duration `json:"p99"`
+	Max     time.Duration `json:"max"`
+}
+
+// Percentile returns the nearest-rank percentile of sorted durations.
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted))/100)) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+func Summarize(timings []*IssueTimings, metric Metric) ElapsedStats {
+	stats := ElapsedStats{}
+	durations := make([]time.Duration, 0, len(timings))
+	var total time.Duration
+	for _, t := range timings {
+		elapsed := t.Get(metric)
+		if !elapsed.Done {
+			stats.Pending++
+			continue
+		}
+		durations = append(durations, elapsed.Duration)
+		total += elapsed.Duration
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	stats.Count = len(durations)
+	if stats.Count > 0 {
+		stats.Mean = total / time.Duration(stats.Count)
+		stats.P50 = Percentile(durations, 50)
+		stats.P90 = Percentile(durations, 90)
+		stats.P99 = Percentile(durations, 99)
+		stats.Max = durations[stats.Count-1]
+	}
+	return stats
+}
+
+type TimingSummary struct {
+	Key    string       `json:"key"`
+	Issues int          `json:"issues"`
+	Triage ElapsedStats `json:"triage"`
+	Owner  ElapsedStats `json:"owner"`
+	Close  ElapsedStats `json:"close"`
+}
+
+func summarizeGroup(key string, timings []*IssueTimings) *TimingSummary {
+	return &TimingSummary{
+		Key:    key,
+		Issues: len(timings),
+		Triage: Summarize(timings, TimeToTriage),
+		Owner:  Summarize(timings, TimeToOwner),
+		Close:  Summarize(timings, TimeToClose),
+	}
+}
+
+// SummarizeBy aggregates timings per group of prop, e.g. per priority,
+// component or owner, ordered by key.
+func SummarizeBy[K comparable](timings []*IssueTimings, prop *common.Property[K]) []*TimingSummary {
+	byIssue := make(map[*gcode.Issue]*IssueTimings, len(timings))
+	issues := make([]*gcode.Issue, len(timings))
+	for i, t := range timings {
+		byIssue[t.Issue] = t
+		issues[i] = t.Issue
+	}
+
+	summaries := make([]*TimingSummary, 0)
+	for _, pair := range common.GroupBy(issues, prop).PairsByValue() {
+		group := make([]*IssueTimings, len(pair.Issues()))
+		for i, issue := range pair.Issues() {
+			group[i] = byIssue[issue]
+		}
+		summaries = append(summaries, summarizeGroup(pair.KeyString(), group))
+	}
+	return summaries
+}
+
+func formatDuration(d time.Duration) string {
+	if d >= 48*time.Hour {
+		return fmt.Sprintf("%.1fd", d.Hours()/24)
+	}
+	return fmt.Sprintf("%.1fh", d.Hours())
+}
+
+func WriteTimingSummaries(w io.Writer, summaries []*TimingSummary, metric Metric) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Group\tDone\tPending\tMean\tP50\tP90\tP99\t\n")
+	for _, summary := range summaries {
+		var stats ElapsedStats
+		switch metric {
+		case TimeToTriage:
+			stats = summary.Triage
+		case TimeToOwner:
+			stats = summary.Owner
+		default:
+			stats = summary.Close
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t\n", summary.Key, stats.Count, stats.Pending,
+			formatDuration(stats.Mean), formatDuration(stats.P50), formatDuration(stats.P90), formatDuration(stats.P99))
+	}
+	return tw.Flush()
+}
+
+// SLA requires Metric to complete Within a duration for issues with one of
+// Priorities (all issues if empty), e.g. P1 triaged within 2 days.
+type SLA struct {
+	Name       string        `json:"name"`
+	Priorities []int         `json:"priorities"`
+	Metric     Metric        `json:"metric"`
+	Within     time.Duration `json:"within"`
+}
+
+func (s *SLA) Applies(issue *gcode.Issue) bool {
+	if len(s.Priorities) == 0 {
+		return true
+	}
+	priority, ok := common.GetIssuePriority(issue)
+	if !ok {
+		return false
+	}
+	for _, p := range s.Priorities {
+		if p == priority {
+			return true
+		}
+	}
+	return false
+}
+
+// SLAResult counts issues that met the SLA, missed it, are still within it
+// (Pending) or are still open past it (Breaching).
+type SLAResult struct {
+	SLA        *SLA           `json:"sla"`
+	Met        int            `json:"met"`
+	Missed     int            `json:"missed"`
+	Pending    int            `json:"pending"`
+	Breaching  int            `json:"breaching"`
+	Violations []*gcode.Issue `json:"-"`
+}
+
+// Compliance is the fraction of decided issues that met the SLA.
+func (r *SLAResult) Compliance() float64 {
+	decided := r.Met + r.Missed + r.Breaching
+	if decided == 0 {
+		return 1
+	}
+	return float64(r.Met) / float64(decided)
+}
+
+func EvaluateSLA(timings []*IssueTimings, sla *SLA) *SLAResult {
+	result := &SLAResult{SLA: sla}
+	for _, t := range timings {
+		if !sla.Applies(t.Issue) {
+			continue
+		}
+		elapsed := t.Get(sla.Metric)
+		switch {
+		case elapsed.Done && elapsed.Duration <= sla.Within:
+			result.Met++
+		case elapsed.Done:
+			result.Missed++
+			result.Violations = append(result.Violations, t.Issue)
+		case elapsed.Duration <= sla.Within:
+			result.Pending++
+		default:
+			result.Breaching++
+			result.Violations = append(result.Violations, t.Issue)
+		}
+	}
+	return result
+}
+
+func EvaluateSLAs(timings []*IssueTimings, slas []*SLA) []*SLAResult {
+	results := make([]*SLAResult, len(slas))
+	for i, sla := range slas {
+		results[i] = EvaluateSLA(timings, sla)
+	}
+	return results
+}
+
+func WriteSLAResults(w io.Writer, results []*SLAResult) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "SLA\tMet\tMissed\tBreaching\tPending\tCompliance\t\n")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%.0f%%\t\n", r.SLA.Name, r.Met, r.Missed, r.Breaching, r.Pending, 100*r.Compliance())
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,44 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPercentileNearestRank(t *testing.T) {
+	sorted := make([]time.Duration, 10)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Hour
+	}
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{0, 1 * time.Hour},
+		{5, 1 * time.Hour},
+		{10, 1 * time.Hour},
+		{11, 2 * time.Hour},
+		{50, 5 * time.Hour},
+		{55, 6 * time.Hour},
+		{90, 9 * time.Hour},
+		{99, 10 * time.Hour},
+		{100, 10 * time.Hour},
+	}
+	for _, test := range tests {
+		if got := Percentile(sorted, test.p); got != test.want {
+			t.Errorf("Percentile(%v) = %v, want %v", test.p, got, test.want)
+		}
+	}
+
+	if got := Percentile(nil, 50); got != 0 {
+		t.Errorf("Percentile of nothing = %v, want 0", got)
+	}
+	// 7% of 100 must not round up to the 8th value
+	hundred := make([]time.Duration, 100)
+	for i := range hundred {
+		hundred[i] = time.Duration(i + 1)
+	}
+	if got := Percentile(hundred, 7); got != 7 {
+		t.Errorf("Percentile(7) of 100 = %v, want 7", got)
+	}
+}
@@ -0,0 +1,109 @@
+package analytics
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tbuckley/go-issuetracker/common"
+	"github.com/tbuckley/go-issuetracker/gcode"
+	"github.com/tbuckley/go-issuetracker/query"
+)
+
+// Metrics maps report metric names, such as "untriaged" or "p1", to values.
+type Metrics map[string]float64
+
+// HealthMetrics computes the Cleanliness and Top priority numbers of the
+// report for a set of open issues. Fields kept in labels are read the way the
+// tracker's search reads them, so the numbers match FetchHealthMetrics: an
+// issue only lacks a field if it has no label for it, an issue labelled with
+// several priorities or milestones counts for each of them, and
+// old_milestone counts each old milestone label of an issue.
+func HealthMetrics(issues []*gcode.Issue, currentMilestone int) Metrics {
+	labelGroups := func(prefix string) *common.StringGroups {
+		return common.GroupStringListProperty(issues, func(issue *gcode.Issue) []string {
+			return common.GetIssueLabelsByPrefix(issue, prefix)
+		})
+	}
+	priorityGroups := labelGroups("Pri-")
+	milestoneGroups := labelGroups("M-")
+	typeGroups := labelGroups("Type-")
+	ownerGroups := common.GroupStringProperty(issues, common.GetIssueOwner)
+	statusGroups := common.GroupStringProperty(issues, common.GetIssueStatus)
+	osGroups := common.GroupStringListProperty(issues, common.GetIssueOSList)
+
+	oldMilestone := 0
+	for label, milestoneIssues := range milestoneGroups.Groups {
+		if milestone, err := strconv.Atoi(label); err == nil && milestone < currentMilestone {
+			oldMilestone += len(milestoneIssues)
+		}
+	}
+
+	launchBugs := func(milestone int) int {
+		launch := 0
+		for _, issue := range milestoneGroups.Groups[strconv.Itoa(milestone)] {
+			for _, issueType := range common.GetIssueLabelsByPrefix(issue, "Type-") {
+				if issueType == "Launch" {
+					launch++
+					break
+				}
+			}
+		}
+		return launch
+	}
+
+	return Metrics{
+		"total":          float64(len(issues)),
+		"untriaged":      float64(len(statusGroups.Groups["Untriaged"])),
+		"no_owner":       float64(len(ownerGroups.None)),
+		"no_milestone":   float64(len(milestoneGroups.None)),
+		"no_priority":    float64(len(priorityGroups.None)),
+		"no_type":        float64(len(typeGroups.None)),
+		"no_status":      float64(len(statusGroups.None)),
+		"no_os":          float64(len(osGroups.None)),
+		"old_milestone":  float64(oldMilestone),
+		"p0":             float64(len(priorityGroups.Groups["0"])),
+		"p1":             float64(len(priorityGroups.Groups["1"])),
+		"launch_current": float64(launchBugs(currentMilestone)),
+		"launch_next":    float64(launchBugs(currentMilestone + 1)),
+	}
+}
+
+// FetchHealthMetrics computes the same metrics as HealthMetrics for the open
+// issues in q's scope by counting them on the server, without fetching them.
+// old_milestone adds up a count per milestone before the current one.
+func FetchHealthMetrics(q *query.Query, currentMilestone int) (Metrics, error) {
+	q = q.Open()
+	queries := map[string]*query.Query{
+		"total":          q,
+		"untriaged":      q.Query("status:Untriaged"),
+		"no_owner":       q.Query("-has:owner"),
+		"no_milestone":   q.Query("-has:M"),
+		"no_priority":    q.Query("-has:Pri"),
+		"no_type":        q.Query("-has:Type"),
+		"no_status":      q.Query("-has:status"),
+		"no_os":          q.Query("-has:OS"),
+		"p0":             q.Query("label:Pri-0"),
+		"p1":             q.Query("label:Pri-1"),
+		"launch_current": q.Query(fmt.Sprintf("label:M-%v label:Type-Launch", currentMilestone)),
+		"launch_next":    q.Query(fmt.Sprintf("label:M-%v label:Type-Launch", currentMilestone+1)),
+	}
+	for milestone := 1; milestone < currentMilestone; milestone++ {
+		queries[fmt.Sprintf("M-%v", milestone)] = q.Query(fmt.Sprintf("label:M-%v", milestone))
+	}
+
+	counts, err := q.WorkGroup().CountAll(queries)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := make(Metrics)
+	for name, count := range counts {
+		if strings.HasPrefix(name, "M-") {
+			metrics["old_milestone"] += float64(count)
+		} else {
+			metrics[name] = float64(count)
+		}
+	}
+	return metrics, nil
+}
@@ -0,0 +1,61 @@
+package analytics
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tbuckley/go-issuetracker/gcode"
+	"github.com/tbuckley/go-issuetracker/offline"
+	"github.com/tbuckley/go-issuetracker/query"
+)
+
+func healthIssue(id int, status, owner string, labels ...string) *gcode.Issue {
+	issue := &gcode.Issue{ID: id, Status: status, Owner: owner, State: "open", Labels: labels}
+	issue.Title = "Issue"
+	return issue
+}
+
+func TestFetchHealthMetricsMatchesHealthMetrics(t *testing.T) {
+	issues := []*gcode.Issue{
+		healthIssue(1, "Untriaged", "", "Cr-UI", "Pri-1", "M-42", "Type-Launch", "OS-Mac"),
+		healthIssue(2, "Available", "a@chromium.org", "Cr-UI", "Pri-0", "M-40", "M-41", "Type-Bug"),
+		// Several values for the same field
+		healthIssue(3, "Assigned", "b@chromium.org", "Cr-UI", "Pri-1", "Pri-2", "M-43", "Type-Bug", "Type-Launch", "OS-Mac", "OS-Linux"),
+		healthIssue(4, "", "", "Cr-UI-Settings"),
+		healthIssue(5, "Untriaged", "", "Cr-UI", "M-4", "OS-All"),
+		healthIssue(6, "Available", "", "Cr-Blink", "Pri-1"),
+	}
+	closed := healthIssue(7, "Fixed", "c@chromium.org", "Cr-UI", "Pri-1", "M-42", "Type-Launch")
+	closed.State = "closed"
+
+	wg := query.NewWorkGroup(4)
+	wg.SetSource(offline.NewSource(append(issues, closed)))
+	fetched, err := FetchHealthMetrics(wg.NewQuery("chromium").Query("Cr:UI"), 42)
+	if err != nil {
+		t.Fatalf("FetchHealthMetrics: %v", err)
+	}
+
+	local := HealthMetrics(issues[:5], 42)
+	if !reflect.DeepEqual(fetched, local) {
+		t.Errorf("counted %v, computed %v", fetched, local)
+	}
+
+	want := Metrics{
+		"total":          5,
+		"untriaged":      2,
+		"no_owner":       3,
+		"no_milestone":   1,
+		"no_priority":    2,
+		"no_type":        2,
+		"no_status":      1,
+		"no_os":          2,
+		"old_milestone":  3,
+		"p0":             1,
+		"p1":             2,
+		"launch_current": 1,
+		"launch_next":    1,
+	}
+	if !reflect.DeepEqual(local, want) {
+		t.Errorf("computed %v, want %v", local, want)
+	}
+}
@@ -0,0 +1,121 @@
+package analytics
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tbuckley/go-issuetracker/common"
+	"github.com/tbuckley/go-issuetracker/gcode"
+	"github.com/tbuckley/go-issuetracker/query"
+)
+
+type TrendPoint struct {
+	Start  time.Time `json:"start"`
+	Label  string    `json:"label"`
+	Opened int       `json:"opened"`
+	Closed int       `json:"closed"`
+	Open   int       `json:"open"`
+}
+
+// Trend holds created-vs-resolved counts per bucket. Baseline is the number of
+// issues open at Start and each point's Open is the running total at the end
+// of its bucket.
+type Trend struct {
+	Start    time.Time     `json:"start"`
+	End      time.Time     `json:"end"`
+	Baseline int           `json:"baseline"`
+	Points   []*TrendPoint `json:"points"`
+}
+
+func isOpenAt(issue *gcode.Issue, t time.Time) bool {
+	published, ok := common.GetIssuePublished(issue)
+	if !ok || !published.Before(t) {
+		return false
+	}
+	closed, ok := common.GetIssueClosed(issue)
+	return !ok || !closed.Before(t)
+}
+
+// CreatedVsResolved computes the trend from a set of issues that includes
+// every issue opened or closed in [start, end) as well as those open at start.
+func CreatedVsResolved(issues []*gcode.Issue, bucketer *common.TimeBucketer, start, end time.Time) *Trend {
+	trend := &Trend{
+		Start:  start,
+		End:    end,
+		Points: make([]*TrendPoint, 0),
+	}
+
+	points := make(map[time.Time]*TrendPoint)
+	for _, bucketStart := range bucketer.Range(start, end) {
+		point := &TrendPoint{Start: bucketStart, Label: bucketer.Label(bucketStart)}
+		points[bucketStart] = point
+		trend.Points = append(trend.Points, point)
+	}
+
+	inRange := func(t time.Time) bool {
+		return !t.Before(start) && t.Before(end)
+	}
+	for _, issue := range issues {
+		if isOpenAt(issue, start) {
+			trend.Baseline++
+		}
+		// A bucketer built without IntervalBuckets may have a zero interval
+		// and no points at all
+		if published, ok := common.GetIssuePublished(issue); ok && inRange(published) {
+			if point, ok := points[bucketer.Start(published)]; ok {
+				point.Opened++
+			}
+		}
+		if closed, ok := common.GetIssueClosed(issue); ok && inRange(closed) {
+			if point, ok := points[bucketer.Start(closed)]; ok {
+				point.Closed++
+			}
+		}
+	}
+
+	open := trend.Baseline
+	for _, point := range trend.Points {
+		open += point.Opened - point.Closed
+		point.Open = open
+	}
+	return trend
+}
+
+// FetchCreatedVsResolved fetches the issues in q's scope needed to compute the
+// trend: those opened or closed in range, and those open at start.
+func FetchCreatedVsResolved(q *query.Query, bucketer *common.TimeBucketer, start, end time.Time) (*Trend, error) {
+	queries := []*query.Query{
+		q.OpenedInRange(start, end),
+		q.ClosedInRange(start, end),
+		q.Open().OpenedBefore(start),
+		q.All().OpenedBefore(start).ClosedAfter(start),
+	}
+
+	seen := make(map[int]bool)
+	issues := make([]*gcode.Issue, 0)
+	for _, q := range queries {
+		fetched, err := query.CollectIssues(q.FetchAllIssues())
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range fetched {
+			if !seen[issue.ID] {
+				seen[issue.ID] = true
+				issues = append(issues, issue)
+			}
+		}
+	}
+	return CreatedVsResolved(issues, bucketer, start, end), nil
+}
+
+func (t *Trend) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Bucket\tOpened\tClosed\tOpen\t\n")
+	fmt.Fprintf(tw, "Start\t\t\t%v\t\n", t.Baseline)
+	for _, point := range t.Points {
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t\n", point.Label, point.Opened, point.Closed, point.Open)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,93 @@
+package bot
+
+import (
+	"errors"
+	"time"
+
+	"github.com/tbuckley/go-issuetracker/bulk"
+	"github.com/tbuckley/go-issuetracker/gcode"
+	"github.com/tbuckley/go-issuetracker/query"
+)
+
+var (
+	ActionLimitReached = errors.New("Reached the maximum number of actions for this run")
+)
+
+type AuditEntry struct {
+	Rule    string
+	IssueID int
+	Diff    []string
+	Time    time.Time
+	DryRun  bool
+	Error   string
+}
+
+// Ledger remembers which rules have been applied to which issues, so that an
+// action is never repeated, and keeps an audit trail of every attempt. Only
+// entries without an Error that aren't DryRun count as applied.
+type Ledger interface {
+	Applied(rule string, issueID int) (bool, error)
+	Record(entry *AuditEntry) error
+}
+
+type Bot struct {
+	Rules     []*Rule
+	Ledger    Ledger
+	NewUpdate func(issue *gcode.Issue) *query.Update
+
+	// MaxActions caps the updates posted per run and Interval spaces them out.
+	MaxActions int
+	Interval   time.Duration
+	DryRun     bool
+}
+
+// Run evaluates every rule against issues and posts the resulting changes. It
+// returns the audit entries recorded during the run.
+func (b *Bot) Run(issues []*gcode.Issue, now time.Time) ([]*AuditEntry, error) {
+	entries := make([]*AuditEntry, 0)
+	actions := 0
+	for _, issue := range issues {
+		for _, rule := range b.Rules {
+			if !rule.When.Matches(issue, now) {
+				continue
+			}
+			applied, err := b.Ledger.Applied(rule.Name, issue.ID)
+			if err != nil {
+				return entries, err
+			}
+			if applied {
+				continue
+			}
+			change := bulk.NewChange(issue, rule.Do.Edits(issue), b.NewUpdate(issue))
+			if change == nil {
+				continue
+			}
+
+			if b.MaxActions > 0 && actions >= b.MaxActions {
+				return entries, ActionLimitReached
+			}
+			if actions > 0 && b.Interval > 0 && !b.DryRun {
+				time.Sleep(b.Interval)
+			}
+			actions++
+
+			entry := &AuditEntry{
+				Rule:    rule.Name,
+				IssueID: issue.ID,
+				Diff:    change.Diff,
+				Time:    time.Now().UTC(),
+				DryRun:  b.DryRun,
+			}
+			if !b.DryRun {
+				if _, err := change.Update.Post(); err != nil {
+					entry.Error = err.Error()
+				}
+			}
+			if err := b.Ledger.Record(entry); err != nil {
+				return entries, err
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
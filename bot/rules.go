@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/tbuckley/go-issuetracker/bulk"
+	"github.com/tbuckley/go-issuetracker/common"
+	"github.com/tbuckley/go-issuetracker/gcode"
+)
+
+// Condition matches an issue when every field that is set matches.
+type Condition struct {
+	Status             string          `json:"status"`
+	HasLabel           string          `json:"hasLabel"`
+	MissingLabelPrefix string          `json:"missingLabelPrefix"`
+	Owned              *bool           `json:"owned"`
+	OlderThan          common.Duration `json:"olderThan"`
+	StaleFor           common.Duration `json:"staleFor"`
+}
+
+func (c *Condition) Matches(issue *gcode.Issue, now time.Time) bool {
+	if c.Status != "" && issue.Status != c.Status {
+		return false
+	}
+	if c.HasLabel != "" && !common.IssueHasLabel(issue, c.HasLabel) {
+		return false
+	}
+	if c.MissingLabelPrefix != "" && len(common.GetIssueLabelsByPrefix(issue, c.MissingLabelPrefix)) > 0 {
+		return false
+	}
+	if c.Owned != nil && *c.Owned != (issue.Owner != "") {
+		return false
+	}
+	if c.OlderThan > 0 {
+		published, ok := common.GetIssuePublished(issue)
+		if !ok || now.Sub(published) < time.Duration(c.OlderThan) {
+			return false
+		}
+	}
+	if c.StaleFor > 0 {
+		updated, ok := common.GetIssueUpdated(issue)
+		if !ok || now.Sub(updated) < time.Duration(c.StaleFor) {
+			return false
+		}
+	}
+	return true
+}
+
+// Action lists the edits a rule makes. ComponentOwners maps a Cr- component
+// such as "UI-Settings" to the user to CC.
+type Action struct {
+	AddLabel        string            `json:"addLabel"`
+	RemoveLabel     string            `json:"removeLabel"`
+	SetStatus       string            `json:"setStatus"`
+	CC              string            `json:"cc"`
+	ComponentOwners map[string]string `json:"componentOwners"`
+	Comment         string            `json:"comment"`
+}
+
+func (a *Action) Edits(issue *gcode.Issue) []bulk.Edit {
+	edits := make([]bulk.Edit, 0)
+	if a.AddLabel != "" {
+		edits = append(edits, &bulk.AddLabel{Label: a.AddLabel})
+	}
+	if a.RemoveLabel != "" {
+		edits = append(edits, &bulk.RemoveLabel{Label: a.RemoveLabel})
+	}
+	if a.SetStatus != "" {
+		edits = append(edits, &bulk.SetStatus{Status: a.SetStatus})
+	}
+	if a.CC != "" {
+		edits = append(edits, &bulk.AddCC{CC: a.CC})
+	}
+	for _, component := range common.GetIssueCrLabels(issue) {
+		if owner, ok := a.ComponentOwners[component]; ok {
+			edits = append(edits, &bulk.AddCC{CC: owner})
+		}
+	}
+	if a.Comment != "" {
+		edits = append(edits, &bulk.Comment{Text: a.Comment})
+	}
+	return edits
+}
+
+type Rule struct {
+	Name string    `json:"name"`
+	When Condition `json:"when"`
+	Do   Action    `json:"do"`
+}
+
+func ParseRules(data []byte) ([]*Rule, error) {
+	rules := make([]*Rule, 0)
+	err := json.Unmarshal(data, &rules)
+	return rules, err
+}
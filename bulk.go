@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/tbuckley/go-issuetracker/bulk"
+	"github.com/tbuckley/go-issuetracker/common"
+	"github.com/tbuckley/go-issuetracker/gcode"
+	"github.com/tbuckley/go-issuetracker/query"
+)
+
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func runBulk(wg *query.WorkGroup, client *http.Client, args []string) {
+	flags := flag.NewFlagSet("bulk", flag.ExitOnError)
+	project := flags.String("project", "chromium", "Project to edit")
+	search := flags.String("query", "", "Search query selecting the issues")
+	label := flags.String("label", "", "Label selecting the issues")
+	beforeMilestone := flags.Int("before-milestone", 0, "Only edit issues with a milestone below this one")
+	setStatus := flags.String("set-status", "", "Status to set")
+	setOwner := flags.String("set-owner", "", "Owner to set")
+	comment := flags.String("comment", "", "Comment to add to every changed issue")
+	dryRun := flags.Bool("dry-run", false, "Only print the preview")
+	yes := flags.Bool("yes", false, "Apply without asking for confirmation")
+	logFile := flags.String("log", "", "Resumable log of applied changes")
+	var addLabels, removeLabels, replaceLabels, addCCs stringList
+	flags.Var(&addLabels, "add-label", "Label to add (repeatable)")
+	flags.Var(&removeLabels, "remove-label", "Label to remove (repeatable)")
+	flags.Var(&replaceLabels, "replace-label", "OLD:NEW label replacement (repeatable)")
+	flags.Var(&addCCs, "add-cc", "User to CC (repeatable)")
+	flags.Parse(args)
+
+	edits := make([]bulk.Edit, 0)
+	for _, replacement := range replaceLabels {
+		parts := strings.SplitN(replacement, ":", 2)
+		if len(parts) != 2 {
+			fmt.Printf("Invalid --replace-label: %v\n", replacement)
+			return
+		}
+		edits = append(edits, &bulk.ReplaceLabel{Old: parts[0], New: parts[1]})
+	}
+	for _, l := range addLabels {
+		edits = append(edits, &bulk.AddLabel{Label: l})
+	}
+	for _, l := range removeLabels {
+		edits = append(edits, &bulk.RemoveLabel{Label: l})
+	}
+	for _, cc := range addCCs {
+		edits = append(edits, &bulk.AddCC{CC: cc})
+	}
+	if *setStatus != "" {
+		edits = append(edits, &bulk.SetStatus{Status: *setStatus})
+	}
+	if *setOwner != "" {
+		edits = append(edits, &bulk.SetOwner{Owner: *setOwner})
+	}
+	if *comment != "" {
+		edits = append(edits, &bulk.Comment{Text: *comment})
+	}
+	if len(edits) == 0 {
+		fmt.Println("No edits given")
+		return
+	}
+
+	q := wg.NewQuery(*project).Client(client)
+	if *search != "" {
+		q = q.Query(*search)
+	}
+	if *label != "" {
+		q = q.Label(*label)
+	}
+	issues, err := query.CollectIssues(q.FetchAllIssues())
+	if err != nil {
+		fmt.Printf("Error: %v\n", err.Error())
+		return
+	}
+	if *beforeMilestone > 0 {
+		issues = GetOldMilestoneIssues(common.GroupIntProperty(issues, common.GetIssueMilestone), *beforeMilestone)
+	}
+
+	plan := bulk.NewPlan(issues, edits, func(issue *gcode.Issue) *query.Update {
+		return wg.NewUpdate(*project, issue.ID).Client(client)
+	})
+
+	var changeLog *bulk.Log
+	if *logFile != "" {
+		changeLog, err = bulk.OpenLog(*logFile, edits)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err.Error())
+			return
+		}
+		defer changeLog.Close()
+
+		remaining := plan.Filter(func(issue *gcode.Issue) bool {
+			return !changeLog.Done(issue.ID)
+		})
+		if applied := len(plan.Changes) - len(remaining.Changes); applied > 0 {
+			fmt.Printf("%v issue(s) already changed according to %v\n", applied, *logFile)
+		}
+		plan = remaining
+	}
+
+	plan.WritePreview(os.Stdout)
+	if *dryRun || len(plan.Changes) == 0 {
+		return
+	}
+	if !*yes && !bulk.Confirm(os.Stdin, os.Stdout, "Apply these changes?") {
+		fmt.Println("Aborted")
+		return
+	}
+	bulk.WriteResults(os.Stdout, plan.Apply(changeLog))
+}
@@ -0,0 +1,151 @@
+package bulk
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/tbuckley/go-issuetracker/gcode"
+	"github.com/tbuckley/go-issuetracker/query"
+)
+
+type Change struct {
+	Issue  *gcode.Issue
+	Update *query.Update
+	Diff   []string
+}
+
+type Plan struct {
+	Changes []*Change
+}
+
+// NewPlan applies edits to every issue, keeping only issues that would change.
+// newUpdate returns the empty update for an issue, with its client and server
+// already configured.
+func NewPlan(issues []*gcode.Issue, edits []Edit, newUpdate func(issue *gcode.Issue) *query.Update) *Plan {
+	plan := &Plan{Changes: make([]*Change, 0)}
+	for _, issue := range issues {
+		if change := NewChange(issue, edits, newUpdate(issue)); change != nil {
+			plan.Changes = append(plan.Changes, change)
+		}
+	}
+	return plan
+}
+
+// NewChange applies edits to a single issue, returning nil if nothing would
+// change. Comments count as a change only when no other kind of edit is given.
+func NewChange(issue *gcode.Issue, edits []Edit, update *query.Update) *Change {
+	diff := make([]string, 0)
+	changed, onlyComments := false, true
+	for _, edit := range edits {
+		var lines []string
+		update, lines = edit.Apply(issue, update)
+		_, isComment := edit.(*Comment)
+		onlyComments = onlyComments && isComment
+		if !isComment && len(lines) > 0 {
+			changed = true
+		}
+		diff = append(diff, lines...)
+	}
+	if !changed && !(onlyComments && len(diff) > 0) {
+		return nil
+	}
+	return &Change{Issue: issue, Update: update, Diff: diff}
+}
+
+// Filter keeps the changes whose issue matches.
+func (p *Plan) Filter(match func(issue *gcode.Issue) bool) *Plan {
+	filtered := &Plan{Changes: make([]*Change, 0, len(p.Changes))}
+	for _, change := range p.Changes {
+		if match(change.Issue) {
+			filtered.Changes = append(filtered.Changes, change)
+		}
+	}
+	return filtered
+}
+
+func (p *Plan) WritePreview(w io.Writer) error {
+	for _, change := range p.Changes {
+		if _, err := fmt.Fprintf(w, "crbug.com/%v: %v\n", change.Issue.ID, change.Issue.Title); err != nil {
+			return err
+		}
+		for _, line := range change.Diff {
+			if _, err := fmt.Fprintf(w, "  %v\n", line); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "%v issue(s) will be changed\n", len(p.Changes))
+	return err
+}
+
+// Confirm asks the user on w and reads a yes/no answer from r.
+func Confirm(r io.Reader, w io.Writer, prompt string) bool {
+	fmt.Fprintf(w, "%v [y/N] ", prompt)
+	answer, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && answer == "" {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+type Result struct {
+	IssueID int
+	Reply   *gcode.Reply
+	Error   error
+	Skipped bool
+}
+
+// Apply posts every change concurrently; the updates share their work group's
+// concurrency limit. Issues already recorded as successful in log are skipped,
+// so an interrupted run can be resumed with the same log. log may be nil.
+func (p *Plan) Apply(log *Log) []*Result {
+	results := make([]*Result, len(p.Changes))
+	wg := new(sync.WaitGroup)
+	for i, change := range p.Changes {
+		if log != nil && log.Done(change.Issue.ID) {
+			results[i] = &Result{IssueID: change.Issue.ID, Skipped: true}
+			continue
+		}
+		wg.Add(1)
+		go func(i int, change *Change) {
+			defer wg.Done()
+			reply, err := change.Update.Post()
+			result := &Result{IssueID: change.Issue.ID, Reply: reply, Error: err}
+			if log != nil {
+				if logErr := log.Record(result); logErr != nil && result.Error == nil {
+					result.Error = logErr
+				}
+			}
+			results[i] = result
+		}(i, change)
+	}
+	wg.Wait()
+	return results
+}
+
+func WriteResults(w io.Writer, results []*Result) error {
+	succeeded, failed, skipped := 0, 0, 0
+	for _, result := range results {
+		var err error
+		switch {
+		case result.Skipped:
+			skipped++
+			_, err = fmt.Fprintf(w, "crbug.com/%v: skipped (already applied)\n", result.IssueID)
+		case result.Error != nil:
+			failed++
+			_, err = fmt.Fprintf(w, "crbug.com/%v: FAILED: %v\n", result.IssueID, result.Error.Error())
+		default:
+			succeeded++
+			_, err = fmt.Fprintf(w, "crbug.com/%v: ok\n", result.IssueID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%v succeeded, %v failed, %v skipped\n", succeeded, failed, skipped)
+	return err
+}
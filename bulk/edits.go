@@ -0,0 +1,108 @@
+package bulk
+
+import (
+	"fmt"
+
+	"github.com/tbuckley/go-issuetracker/common"
+	"github.com/tbuckley/go-issuetracker/gcode"
+	"github.com/tbuckley/go-issuetracker/query"
+)
+
+// Edit adds a change to update if it would modify issue, and returns the
+// preview lines describing the change.
+type Edit interface {
+	Apply(issue *gcode.Issue, update *query.Update) (*query.Update, []string)
+}
+
+type AddLabel struct {
+	Label string
+}
+
+func (e *AddLabel) Apply(issue *gcode.Issue, update *query.Update) (*query.Update, []string) {
+	if common.IssueHasLabel(issue, e.Label) {
+		return update, nil
+	}
+	return update.AddLabel(e.Label), []string{"+ label " + e.Label}
+}
+
+type RemoveLabel struct {
+	Label string
+}
+
+func (e *RemoveLabel) Apply(issue *gcode.Issue, update *query.Update) (*query.Update, []string) {
+	if !common.IssueHasLabel(issue, e.Label) {
+		return update, nil
+	}
+	return update.RemoveLabel(e.Label), []string{"- label " + e.Label}
+}
+
+// ReplaceLabel swaps Old for New on issues that have Old, e.g. bumping M-41 to
+// M-42.
+type ReplaceLabel struct {
+	Old string
+	New string
+}
+
+func (e *ReplaceLabel) Apply(issue *gcode.Issue, update *query.Update) (*query.Update, []string) {
+	if !common.IssueHasLabel(issue, e.Old) {
+		return update, nil
+	}
+	diff := []string{"- label " + e.Old}
+	update = update.RemoveLabel(e.Old)
+	if !common.IssueHasLabel(issue, e.New) {
+		diff = append(diff, "+ label "+e.New)
+		update = update.AddLabel(e.New)
+	}
+	return update, diff
+}
+
+type SetStatus struct {
+	Status string
+}
+
+func (e *SetStatus) Apply(issue *gcode.Issue, update *query.Update) (*query.Update, []string) {
+	if issue.Status == e.Status {
+		return update, nil
+	}
+	return update.SetStatus(e.Status), []string{fmt.Sprintf("~ status %v -> %v", displayValue(issue.Status), e.Status)}
+}
+
+type SetOwner struct {
+	Owner string
+}
+
+func (e *SetOwner) Apply(issue *gcode.Issue, update *query.Update) (*query.Update, []string) {
+	if issue.Owner == e.Owner {
+		return update, nil
+	}
+	return update.SetOwner(e.Owner), []string{fmt.Sprintf("~ owner %v -> %v", displayValue(issue.Owner), e.Owner)}
+}
+
+type AddCC struct {
+	CC string
+}
+
+func (e *AddCC) Apply(issue *gcode.Issue, update *query.Update) (*query.Update, []string) {
+	for _, cc := range issue.CCs {
+		if cc == e.CC {
+			return update, nil
+		}
+	}
+	return update.AddCC(e.CC), []string{"+ cc " + e.CC}
+}
+
+// Comment is added to every changed issue. On its own it changes every issue.
+type Comment struct {
+	Text string
+}
+
+func (e *Comment) Apply(issue *gcode.Issue, update *query.Update) (*query.Update, []string) {
+	return update.Comment(e.Text), []string{"# comment: " + e.Text}
+}
+
+func displayValue(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
@@ -0,0 +1,97 @@
+package bulk
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+type logEntry struct {
+	IssueID int       `json:"issue"`
+	Edits   string    `json:"edits"`
+	OK      bool      `json:"ok"`
+	Error   string    `json:"error,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// EditsKey identifies a set of edits, so a log only resumes the bulk run that
+// wrote it.
+func EditsKey(edits []Edit) string {
+	hash := sha256.New()
+	for _, edit := range edits {
+		fmt.Fprintf(hash, "%T %+v\n", edit, edit)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Log is an append-only JSON Lines record of applied changes, used to resume
+// a bulk run without re-applying edits. Entries are keyed by the edits, so
+// the same file can be reused for a different bulk run without skipping
+// issues.
+type Log struct {
+	mu   sync.Mutex
+	file *os.File
+	key  string
+	done map[int]bool
+}
+
+func OpenLog(path string, edits []Edit) (*Log, error) {
+	log := &Log{key: EditsKey(edits), done: make(map[int]bool)}
+
+	existing, err := os.Open(path)
+	if err == nil {
+		scanner := bufio.NewScanner(existing)
+		for scanner.Scan() {
+			entry := new(logEntry)
+			if json.Unmarshal(scanner.Bytes(), entry) == nil && entry.OK && entry.Edits == log.key {
+				log.done[entry.IssueID] = true
+			}
+		}
+		existing.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	log.file, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return log, nil
+}
+
+func (l *Log) Done(issueID int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.done[issueID]
+}
+
+func (l *Log) Record(result *Result) error {
+	entry := &logEntry{IssueID: result.IssueID, Edits: l.key, OK: result.Error == nil, Time: time.Now().UTC()}
+	if result.Error != nil {
+		entry.Error = result.Error.Error()
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if entry.OK {
+		l.done[result.IssueID] = true
+	}
+	_, err = l.file.Write(append(data, '\n'))
+	return err
+}
+
+func (l *Log) Close() error {
+	return l.file.Close()
+}
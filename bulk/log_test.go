@@ -0,0 +1,38 @@
+package bulk
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLogOnlyResumesSameEdits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bulk.log")
+	edits := []Edit{&AddLabel{Label: "Hotlist-Triage"}}
+
+	log, err := OpenLog(path, edits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Record(&Result{IssueID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	log.Close()
+
+	same, err := OpenLog(path, []Edit{&AddLabel{Label: "Hotlist-Triage"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer same.Close()
+	if !same.Done(1) {
+		t.Errorf("issue 1 not done when resuming the same edits")
+	}
+
+	other, err := OpenLog(path, []Edit{&AddLabel{Label: "Hotlist-Other"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if other.Done(1) {
+		t.Errorf("issue 1 done for a different set of edits")
+	}
+}
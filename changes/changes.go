@@ -0,0 +1,282 @@
+package changes
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tbuckley/go-issuetracker/common"
+	"github.com/tbuckley/go-issuetracker/gcode"
+)
+
+type Kind string
+
+const (
+	Added            Kind = "added"
+	Removed          Kind = "removed"
+	Closed           Kind = "closed"
+	Reopened         Kind = "reopened"
+	NewlyP1          Kind = "newly-p1"
+	PriorityRaised   Kind = "priority-raised"
+	PriorityLowered  Kind = "priority-lowered"
+	OwnerChanged     Kind = "owner-changed"
+	StatusChanged    Kind = "status-changed"
+	MilestoneChanged Kind = "milestone-changed"
+	NewLaunchBug     Kind = "new-launch-bug"
+	LabelsChanged    Kind = "labels-changed"
+	TitleChanged     Kind = "title-changed"
+)
+
+// Kinds lists every kind of change in the order they are reported.
+var Kinds = []Kind{
+	NewlyP1, NewLaunchBug, Added, Closed, Reopened, Removed, PriorityRaised,
+	PriorityLowered, MilestoneChanged, OwnerChanged, StatusChanged,
+	LabelsChanged, TitleChanged,
+}
+
+var kindTitles = map[Kind]string{
+	Added:            "New issues",
+	Removed:          "No longer in the set",
+	Closed:           "Newly closed",
+	Reopened:         "Reopened",
+	NewlyP1:          "Newly P1",
+	PriorityRaised:   "Priority raised",
+	PriorityLowered:  "Priority lowered",
+	OwnerChanged:     "Owner changed",
+	StatusChanged:    "Status changed",
+	MilestoneChanged: "Milestone changed",
+	NewLaunchBug:     "New launch bugs",
+	LabelsChanged:    "Labels changed",
+	TitleChanged:     "Title changed",
+}
+
+func (k Kind) Title() string {
+	return kindTitles[k]
+}
+
+type Change struct {
+	Kind    Kind   `json:"kind"`
+	IssueID int    `json:"issue"`
+	Title   string `json:"title"`
+	Before  string `json:"before,omitempty"`
+	After   string `json:"after,omitempty"`
+}
+
+type Changelog struct {
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Changes []*Change `json:"changes"`
+}
+
+func intString(value int, ok bool) string {
+	if !ok {
+		return ""
+	}
+	return strconv.Itoa(value)
+}
+
+func isLaunchBug(issue *gcode.Issue) (int, bool) {
+	issueType, _ := common.GetIssueType(issue)
+	milestone, ok := common.GetIssueMilestone(issue)
+	return milestone, ok && issueType == "Launch"
+}
+
+func labelDiff(before, after *gcode.Issue) (string, string) {
+	inBefore := make(map[string]bool)
+	for _, label := range before.Labels {
+		inBefore[label] = true
+	}
+	inAfter := make(map[string]bool)
+	for _, label := range after.Labels {
+		inAfter[label] = true
+	}
+	removed := make([]string, 0)
+	for _, label := range before.Labels {
+		if !inAfter[label] {
+			removed = append(removed, label)
+		}
+	}
+	added := make([]string, 0)
+	for _, label := range after.Labels {
+		if !inBefore[label] {
+			added = append(added, label)
+		}
+	}
+	return strings.Join(removed, " "), strings.Join(added, " ")
+}
+
+// Diff compares two sets of issues, keyed by ID, and classifies every change.
+// Issues missing from after are reported as Removed since a snapshot of open
+// issues can't tell closing apart from relabeling.
+func Diff(before, after []*gcode.Issue, from, to time.Time) *Changelog {
+	log := &Changelog{From: from, To: to, Changes: make([]*Change, 0)}
+	add := func(kind Kind, issue *gcode.Issue, beforeValue, afterValue string) {
+		log.Changes = append(log.Changes, &Change{
+			Kind:    kind,
+			IssueID: issue.ID,
+			Title:   issue.Title,
+			Before:  beforeValue,
+			After:   afterValue,
+		})
+	}
+
+	beforeByID := make(map[int]*gcode.Issue, len(before))
+	for _, issue := range before {
+		beforeByID[issue.ID] = issue
+	}
+	afterByID := make(map[int]*gcode.Issue, len(after))
+	for _, issue := range after {
+		afterByID[issue.ID] = issue
+	}
+
+	for _, b := range before {
+		if _, ok := afterByID[b.ID]; !ok {
+			add(Removed, b, b.State, "")
+		}
+	}
+
+	for _, a := range after {
+		newPriority, newOk := common.GetIssuePriority(a)
+		newMilestone, newLaunch := isLaunchBug(a)
+
+		b, existed := beforeByID[a.ID]
+		if !existed {
+			add(Added, a, "", a.Status)
+			if newOk && newPriority == 1 {
+				add(NewlyP1, a, "", "1")
+			}
+			if newLaunch {
+				add(NewLaunchBug, a, "", "M-"+strconv.Itoa(newMilestone))
+			}
+			continue
+		}
+
+		if b.State != "closed" && a.State == "closed" {
+			add(Closed, a, b.Status, a.Status)
+		} else if b.State == "closed" && a.State != "closed" {
+			add(Reopened, a, b.Status, a.Status)
+		} else if b.Status != a.Status {
+			add(StatusChanged, a, b.Status, a.Status)
+		}
+
+		oldPriority, oldOk := common.GetIssuePriority(b)
+		switch {
+		case newOk && (!oldOk || newPriority < oldPriority):
+			add(PriorityRaised, a, intString(oldPriority, oldOk), intString(newPriority, newOk))
+		case oldOk && (!newOk || newPriority > oldPriority):
+			add(PriorityLowered, a, intString(oldPriority, oldOk), intString(newPriority, newOk))
+		}
+		if newOk && newPriority == 1 && (!oldOk || oldPriority != 1) {
+			add(NewlyP1, a, intString(oldPriority, oldOk), "1")
+		}
+
+		oldMilestone, oldMilestoneOk := common.GetIssueMilestone(b)
+		milestone, milestoneOk := common.GetIssueMilestone(a)
+		if oldMilestoneOk != milestoneOk || oldMilestone != milestone {
+			add(MilestoneChanged, a, intString(oldMilestone, oldMilestoneOk), intString(milestone, milestoneOk))
+		}
+		if oldLaunchMilestone, oldLaunch := isLaunchBug(b); newLaunch && (!oldLaunch || oldLaunchMilestone != newMilestone) {
+			add(NewLaunchBug, a, "", "M-"+strconv.Itoa(newMilestone))
+		}
+
+		if b.Owner != a.Owner {
+			add(OwnerChanged, a, b.Owner, a.Owner)
+		}
+		if removed, added := labelDiff(b, a); removed != "" || added != "" {
+			add(LabelsChanged, a, removed, added)
+		}
+		if b.Title != a.Title {
+			add(TitleChanged, a, b.Title, a.Title)
+		}
+	}
+
+	sort.SliceStable(log.Changes, func(i, j int) bool {
+		return log.Changes[i].IssueID < log.Changes[j].IssueID
+	})
+	return log
+}
+
+func (c *Changelog) ByKind(kind Kind) []*Change {
+	matched := make([]*Change, 0)
+	for _, change := range c.Changes {
+		if change.Kind == kind {
+			matched = append(matched, change)
+		}
+	}
+	return matched
+}
+
+// NewLaunchBugs returns the launch bugs newly targeted at milestone.
+func (c *Changelog) NewLaunchBugs(milestone int) []*Change {
+	matched := make([]*Change, 0)
+	for _, change := range c.ByKind(NewLaunchBug) {
+		if change.After == "M-"+strconv.Itoa(milestone) {
+			matched = append(matched, change)
+		}
+	}
+	return matched
+}
+
+func (c *Change) Transition() string {
+	switch {
+	case c.Before == "" && c.After == "":
+		return ""
+	case c.Before == "":
+		return c.After
+	case c.After == "":
+		return "was " + c.Before
+	case c.Kind == LabelsChanged:
+		return "-" + strings.Replace(c.Before, " ", " -", -1) + " +" + strings.Replace(c.After, " ", " +", -1)
+	}
+	return c.Before + " -> " + c.After
+}
+
+func (c *Changelog) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "Changes from %v to %v\n", c.From.Format("2006-01-02"), c.To.Format("2006-01-02"))
+	for _, kind := range Kinds {
+		changes := c.ByKind(kind)
+		if len(changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n== %v (%v) ==\n", kind.Title(), len(changes))
+		for _, change := range changes {
+			fmt.Fprintf(w, "crbug.com/%v: %v", change.IssueID, change.Title)
+			if transition := change.Transition(); transition != "" {
+				fmt.Fprintf(w, " [%v]", transition)
+			}
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func escapeMarkdown(s string) string {
+	replacer := strings.NewReplacer("\\", "\\\\", "*", "\\*", "_", "\\_", "[", "\\[", "]", "\\]", "`", "\\`")
+	return replacer.Replace(s)
+}
+
+func (c *Changelog) WriteMarkdown(w io.Writer) error {
+	fmt.Fprintf(w, "# Changes from %v to %v\n", c.From.Format("2006-01-02"), c.To.Format("2006-01-02"))
+	for _, kind := range Kinds {
+		changes := c.ByKind(kind)
+		if len(changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n## %v (%v)\n\n", kind.Title(), len(changes))
+		for _, change := range changes {
+			fmt.Fprintf(w, "* [crbug.com/%v](https://crbug.com/%v) %v", change.IssueID, change.IssueID, escapeMarkdown(change.Title))
+			if transition := change.Transition(); transition != "" {
+				fmt.Fprintf(w, " — `%v`", transition)
+			}
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
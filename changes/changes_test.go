@@ -0,0 +1,88 @@
+package changes
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tbuckley/go-issuetracker/gcode"
+)
+
+func issue(id int, state, status, owner string, labels ...string) *gcode.Issue {
+	return &gcode.Issue{
+		Entry:  gcode.Entry{Title: "Issue"},
+		ID:     id,
+		State:  state,
+		Status: status,
+		Owner:  owner,
+		Labels: labels,
+	}
+}
+
+func kinds(log *Changelog, id int) []Kind {
+	found := make([]Kind, 0)
+	for _, change := range log.Changes {
+		if change.IssueID == id {
+			found = append(found, change.Kind)
+		}
+	}
+	return found
+}
+
+func TestDiff(t *testing.T) {
+	before := []*gcode.Issue{
+		issue(1, "open", "Untriaged", "", "Pri-2"),
+		issue(2, "open", "Assigned", "a@chromium.org", "Pri-1"),
+		issue(3, "open", "Assigned", "a@chromium.org", "Pri-2", "M-40"),
+		issue(4, "closed", "Fixed", "a@chromium.org"),
+		issue(5, "open", "Available", ""),
+	}
+	after := []*gcode.Issue{
+		issue(1, "open", "Untriaged", "", "Pri-1"),
+		issue(2, "closed", "Fixed", "b@chromium.org", "Pri-1"),
+		issue(3, "open", "Assigned", "a@chromium.org", "Pri-3", "M-41", "Type-Launch"),
+		issue(4, "open", "Assigned", "a@chromium.org"),
+		issue(6, "open", "Untriaged", "", "Pri-1", "Type-Launch", "M-42"),
+	}
+	from := time.Date(2015, 2, 4, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+	log := Diff(before, after, from, to)
+
+	if !log.From.Equal(from) || !log.To.Equal(to) {
+		t.Errorf("range = %v - %v, want %v - %v", log.From, log.To, from, to)
+	}
+	want := map[int][]Kind{
+		1: {PriorityRaised, NewlyP1, LabelsChanged},
+		2: {Closed, OwnerChanged},
+		3: {PriorityLowered, MilestoneChanged, NewLaunchBug, LabelsChanged},
+		4: {Reopened},
+		5: {Removed},
+		6: {Added, NewlyP1, NewLaunchBug},
+	}
+	for id, expected := range want {
+		if got := kinds(log, id); !reflect.DeepEqual(got, expected) {
+			t.Errorf("changes of %v = %v, want %v", id, got, expected)
+		}
+	}
+	for i := 1; i < len(log.Changes); i++ {
+		if log.Changes[i-1].IssueID > log.Changes[i].IssueID {
+			t.Fatalf("changes not sorted by issue")
+		}
+	}
+
+	if launch := log.NewLaunchBugs(42); len(launch) != 1 || launch[0].IssueID != 6 {
+		t.Errorf("NewLaunchBugs(42) = %v, want issue 6", launch)
+	}
+	for _, change := range log.ByKind(LabelsChanged) {
+		if change.IssueID == 3 && change.Transition() != "-Pri-2 -M-40 +Pri-3 +M-41 +Type-Launch" {
+			t.Errorf("labels transition = %q", change.Transition())
+		}
+	}
+}
+
+func TestDiffUnchanged(t *testing.T) {
+	issues := []*gcode.Issue{issue(1, "open", "Assigned", "a@chromium.org", "Pri-1")}
+	if log := Diff(issues, issues, time.Time{}, time.Time{}); len(log.Changes) != 0 {
+		t.Errorf("unchanged issues produced %v changes", len(log.Changes))
+	}
+}
@@ -0,0 +1,190 @@
+package common
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tbuckley/go-issuetracker/gcode"
+)
+
+type BucketMode int
+
+const (
+	BucketDay BucketMode = iota
+	BucketWeek
+	BucketMonth
+	BucketQuarter
+	BucketInterval
+)
+
+// TimeBucketer maps timestamps onto calendar buckets. Day, week, month and
+// quarter buckets are computed in Location; interval buckets are fixed-width
+// durations counted from Origin.
+type TimeBucketer struct {
+	Mode      BucketMode
+	Location  *time.Location
+	WeekStart time.Weekday
+	Interval  time.Duration
+	Origin    time.Time
+}
+
+func DayBuckets(loc *time.Location) *TimeBucketer {
+	return &TimeBucketer{Mode: BucketDay, Location: loc}
+}
+
+// WeekBuckets returns ISO weeks, which start on Monday.
+func WeekBuckets(loc *time.Location) *TimeBucketer {
+	return &TimeBucketer{Mode: BucketWeek, Location: loc, WeekStart: time.Monday}
+}
+
+func WeekBucketsStartingOn(loc *time.Location, start time.Weekday) *TimeBucketer {
+	return &TimeBucketer{Mode: BucketWeek, Location: loc, WeekStart: start}
+}
+
+func MonthBuckets(loc *time.Location) *TimeBucketer {
+	return &TimeBucketer{Mode: BucketMonth, Location: loc}
+}
+
+func QuarterBuckets(loc *time.Location) *TimeBucketer {
+	return &TimeBucketer{Mode: BucketQuarter, Location: loc}
+}
+
+func IntervalBuckets(origin time.Time, interval time.Duration) (*TimeBucketer, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("Invalid bucket interval: %v", interval)
+	}
+	return &TimeBucketer{Mode: BucketInterval, Origin: origin, Interval: interval}, nil
+}
+
+func (b *TimeBucketer) location() *time.Location {
+	if b.Location == nil {
+		return time.UTC
+	}
+	return b.Location
+}
+
+// Start returns the beginning of the bucket containing t.
+func (b *TimeBucketer) Start(t time.Time) time.Time {
+	t = t.In(b.location())
+	year, month, day := t.Date()
+	switch b.Mode {
+	case BucketDay:
+		return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+	case BucketWeek:
+		offset := (int(t.Weekday()) - int(b.WeekStart) + 7) % 7
+		return time.Date(year, month, day-offset, 0, 0, 0, 0, t.Location())
+	case BucketMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+	case BucketQuarter:
+		quarterMonth := time.Month((int(month)-1)/3*3 + 1)
+		return time.Date(year, quarterMonth, 1, 0, 0, 0, 0, t.Location())
+	case BucketInterval:
+		if b.Interval <= 0 {
+			return t
+		}
+		elapsed := t.Sub(b.Origin)
+		n := elapsed / b.Interval
+		if elapsed < 0 && elapsed%b.Interval != 0 {
+			n--
+		}
+		return b.Origin.Add(n * b.Interval).In(t.Location())
+	}
+	return t
+}
+
+// Next returns the beginning of the bucket following the one starting at start.
+func (b *TimeBucketer) Next(start time.Time) time.Time {
+	switch b.Mode {
+	case BucketDay:
+		return start.AddDate(0, 0, 1)
+	case BucketWeek:
+		return start.AddDate(0, 0, 7)
+	case BucketMonth:
+		return start.AddDate(0, 1, 0)
+	case BucketQuarter:
+		return start.AddDate(0, 3, 0)
+	case BucketInterval:
+		if b.Interval > 0 {
+			return start.Add(b.Interval)
+		}
+	}
+	return start
+}
+
+// Label formats the bucket starting at start, e.g. "2015-W07" or "2015-Q1".
+func (b *TimeBucketer) Label(start time.Time) string {
+	switch b.Mode {
+	case BucketWeek:
+		if b.WeekStart == time.Monday {
+			year, week := start.ISOWeek()
+			return fmt.Sprintf("%04d-W%02d", year, week)
+		}
+		return start.Format("2006-01-02")
+	case BucketMonth:
+		return start.Format("2006-01")
+	case BucketQuarter:
+		return fmt.Sprintf("%04d-Q%d", start.Year(), (int(start.Month())-1)/3+1)
+	case BucketInterval:
+		return start.Format("2006-01-02 15:04")
+	}
+	return start.Format("2006-01-02")
+}
+
+// Range returns the start of every bucket overlapping [from, to).
+func (b *TimeBucketer) Range(from, to time.Time) []time.Time {
+	starts := make([]time.Time, 0)
+	for start := b.Start(from); start.Before(to); {
+		starts = append(starts, start)
+		next := b.Next(start)
+		if !next.After(start) {
+			break
+		}
+		start = next
+	}
+	return starts
+}
+
+// ContiguousPairs returns one pair per bucket between the earliest and latest
+// groups, including empty buckets, followed by the None pair if any.
+func (b *TimeBucketer) ContiguousPairs(g *TimeGroups) []IssuePair {
+	pairs := make([]IssuePair, 0, len(g.Groups)+1)
+	var first, last time.Time
+	for key := range g.Groups {
+		if first.IsZero() || key.Before(first) {
+			first = key
+		}
+		if last.IsZero() || key.After(last) {
+			last = key
+		}
+	}
+	if len(g.Groups) > 0 {
+		for _, start := range b.Range(first, b.Next(last)) {
+			pairs = append(pairs, g.Pair(start))
+		}
+	}
+	if len(g.None) > 0 {
+		pairs = append(pairs, g.NonePair())
+	}
+	return pairs
+}
+
+// TimeBucketProperty keys issues by the start of the bucket containing the
+// time returned by propFunc.
+func TimeBucketProperty(name string, propFunc TimePropertyFunc, bucketer *TimeBucketer) *Property[time.Time] {
+	return &Property[time.Time]{
+		Name: name,
+		Keys: Scalar(func(entry *gcode.Issue) (time.Time, bool) {
+			val, ok := propFunc(entry)
+			if !ok {
+				return time.Time{}, false
+			}
+			return bucketer.Start(val), true
+		}),
+		Less:   TimeLess,
+		Format: bucketer.Label,
+	}
+}
+
+func GroupTimePropertyByBucket(entries []*gcode.Issue, propFunc TimePropertyFunc, bucketer *TimeBucketer) *TimeGroups {
+	return GroupBy(entries, TimeBucketProperty("", propFunc, bucketer))
+}
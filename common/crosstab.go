@@ -0,0 +1,208 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/tbuckley/go-issuetracker/gcode"
+)
+
+// CrossTable counts issues by two properties at once. Rows and Columns are the
+// groups of each property (including None) and Cells[i][j] holds the issues in
+// both Rows[i] and Columns[j].
+type CrossTable struct {
+	RowName    string
+	ColumnName string
+	Rows       []IssuePair
+	Columns    []IssuePair
+	Cells      [][][]*gcode.Issue
+	Issues     []*gcode.Issue
+}
+
+func CrossTab[R comparable, C comparable](issues []*gcode.Issue, rowProp *Property[R], colProp *Property[C]) *CrossTable {
+	rows := GroupBy(issues, rowProp).PairsByValue()
+	cols := GroupBy(issues, colProp).PairsByValue()
+
+	colsByIssue := make(map[*gcode.Issue][]int)
+	for j, col := range cols {
+		for _, issue := range col.Issues() {
+			colsByIssue[issue] = append(colsByIssue[issue], j)
+		}
+	}
+
+	cells := make([][][]*gcode.Issue, len(rows))
+	for i, row := range rows {
+		cells[i] = make([][]*gcode.Issue, len(cols))
+		for _, issue := range row.Issues() {
+			for _, j := range colsByIssue[issue] {
+				cells[i][j] = append(cells[i][j], issue)
+			}
+		}
+	}
+
+	return &CrossTable{
+		RowName:    rowProp.Name,
+		ColumnName: colProp.Name,
+		Rows:       rows,
+		Columns:    cols,
+		Cells:      cells,
+		Issues:     issues,
+	}
+}
+
+func (t *CrossTable) Count(row, col int) int {
+	return len(t.Cells[row][col])
+}
+
+// RowTotal counts distinct issues in a row, which may be less than the sum of
+// its cells when the column property is multi-valued.
+func (t *CrossTable) RowTotal(row int) int {
+	return len(t.Rows[row].Issues())
+}
+
+func (t *CrossTable) ColumnTotal(col int) int {
+	return len(t.Columns[col].Issues())
+}
+
+func (t *CrossTable) Total() int {
+	return len(t.Issues)
+}
+
+func (t *CrossTable) SortRows(less func(a, b IssuePair) bool) {
+	order := make([]int, len(t.Rows))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return less(t.Rows[order[i]], t.Rows[order[j]])
+	})
+
+	rows := make([]IssuePair, len(order))
+	cells := make([][][]*gcode.Issue, len(order))
+	for i, k := range order {
+		rows[i] = t.Rows[k]
+		cells[i] = t.Cells[k]
+	}
+	t.Rows, t.Cells = rows, cells
+}
+
+func (t *CrossTable) SortColumns(less func(a, b IssuePair) bool) {
+	order := make([]int, len(t.Columns))
+	for j := range order {
+		order[j] = j
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return less(t.Columns[order[i]], t.Columns[order[j]])
+	})
+
+	cols := make([]IssuePair, len(order))
+	for j, k := range order {
+		cols[j] = t.Columns[k]
+	}
+	for i, row := range t.Cells {
+		cells := make([][]*gcode.Issue, len(order))
+		for j, k := range order {
+			cells[j] = row[k]
+		}
+		t.Cells[i] = cells
+	}
+	t.Columns = cols
+}
+
+func ByValue(a, b IssuePair) bool {
+	return a.HasKeyLessThan(b)
+}
+
+func ByNumEntries(a, b IssuePair) bool {
+	return len(a.Issues()) < len(b.Issues())
+}
+
+func (t *CrossTable) SortRowsByValue() {
+	t.SortRows(ByValue)
+}
+
+func (t *CrossTable) SortRowsByNumEntries() {
+	t.SortRows(ByNumEntries)
+}
+
+func (t *CrossTable) SortColumnsByValue() {
+	t.SortColumns(ByValue)
+}
+
+func (t *CrossTable) SortColumnsByNumEntries() {
+	t.SortColumns(ByNumEntries)
+}
+
+func (t *CrossTable) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+
+	fmt.Fprintf(tw, "%v \\ %v\t", t.RowName, t.ColumnName)
+	for _, col := range t.Columns {
+		fmt.Fprintf(tw, "%v\t", col.KeyString())
+	}
+	fmt.Fprintf(tw, "Total\t\n")
+
+	for i, row := range t.Rows {
+		fmt.Fprintf(tw, "%v\t", row.KeyString())
+		for j := range t.Columns {
+			fmt.Fprintf(tw, "%v\t", t.Count(i, j))
+		}
+		fmt.Fprintf(tw, "%v\t\n", t.RowTotal(i))
+	}
+
+	fmt.Fprintf(tw, "Total\t")
+	for j := range t.Columns {
+		fmt.Fprintf(tw, "%v\t", t.ColumnTotal(j))
+	}
+	fmt.Fprintf(tw, "%v\t\n", t.Total())
+
+	return tw.Flush()
+}
+
+type crossTableJSON struct {
+	RowName      string    `json:"rowName"`
+	ColumnName   string    `json:"columnName"`
+	Rows         []string  `json:"rows"`
+	Columns      []string  `json:"columns"`
+	Counts       [][]int   `json:"counts"`
+	Issues       [][][]int `json:"issues"`
+	RowTotals    []int     `json:"rowTotals"`
+	ColumnTotals []int     `json:"columnTotals"`
+	Total        int       `json:"total"`
+}
+
+func (t *CrossTable) MarshalJSON() ([]byte, error) {
+	out := crossTableJSON{
+		RowName:      t.RowName,
+		ColumnName:   t.ColumnName,
+		Rows:         make([]string, len(t.Rows)),
+		Columns:      make([]string, len(t.Columns)),
+		Counts:       make([][]int, len(t.Rows)),
+		Issues:       make([][][]int, len(t.Rows)),
+		RowTotals:    make([]int, len(t.Rows)),
+		ColumnTotals: make([]int, len(t.Columns)),
+		Total:        t.Total(),
+	}
+	for j, col := range t.Columns {
+		out.Columns[j] = col.KeyString()
+		out.ColumnTotals[j] = t.ColumnTotal(j)
+	}
+	for i, row := range t.Rows {
+		out.Rows[i] = row.KeyString()
+		out.RowTotals[i] = t.RowTotal(i)
+		out.Counts[i] = make([]int, len(t.Columns))
+		out.Issues[i] = make([][]int, len(t.Columns))
+		for j := range t.Columns {
+			out.Counts[i][j] = t.Count(i, j)
+			ids := make([]int, len(t.Cells[i][j]))
+			for k, issue := range t.Cells[i][j] {
+				ids[k] = issue.ID
+			}
+			out.Issues[i][j] = ids
+		}
+	}
+	return json.Marshal(out)
+}
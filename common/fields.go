@@ -0,0 +1,144 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tbuckley/go-issuetracker/gcode"
+)
+
+// Field is a property that can be referenced by name, e.g. as a column or a
+// sort key. Values returns the formatted values of the property for an issue
+// (none if unset) and Less orders two issues by it, with unset values last.
+// Multi is set for fields that can hold several values, such as labels.
+type Field struct {
+	Name   string
+	Values func(entry *gcode.Issue) []string
+	Less   func(a, b *gcode.Issue) bool
+	Multi  bool
+}
+
+// Field erases the key type of the property so that it can be registered.
+func (p *Property[K]) Field() *Field {
+	first := func(entry *gcode.Issue) (K, bool) {
+		keys := p.Keys(entry)
+		if len(keys) == 0 {
+			var zero K
+			return zero, false
+		}
+		return keys[0], true
+	}
+	format := p.Format
+	if format == nil {
+		format = func(key K) string { return fmt.Sprint(key) }
+	}
+	return &Field{
+		Name:  p.Name,
+		Multi: p.Multi,
+		Values: func(entry *gcode.Issue) []string {
+			keys := p.Keys(entry)
+			values := make([]string, len(keys))
+			for i, key := range keys {
+				values[i] = format(key)
+			}
+			return values
+		},
+		Less: func(a, b *gcode.Issue) bool {
+			aKey, aOk := first(a)
+			bKey, bOk := first(b)
+			switch {
+			case !bOk:
+				return aOk
+			case !aOk:
+				return false
+			default:
+				return p.Less(aKey, bKey)
+			}
+		},
+	}
+}
+
+var (
+	fieldsLock = new(sync.RWMutex)
+	fields     = make(map[string]*Field)
+)
+
+func RegisterField(field *Field) {
+	fieldsLock.Lock()
+	defer fieldsLock.Unlock()
+	fields[strings.ToLower(field.Name)] = field
+}
+
+// LookupField finds a registered field by name. Names of the form
+// "label:Prefix-" refer to the labels with that prefix.
+func LookupField(name string) (*Field, bool) {
+	lower := strings.ToLower(name)
+	if strings.HasPrefix(lower, "label:") {
+		prefix := name[len("label:"):]
+		return StringListProperty(name, func(entry *gcode.Issue) []string {
+			return GetIssueLabelsByPrefix(entry, prefix)
+		}).Field(), true
+	}
+
+	fieldsLock.RLock()
+	defer fieldsLock.RUnlock()
+	field, ok := fields[lower]
+	return field, ok
+}
+
+func FieldNames() []string {
+	fieldsLock.RLock()
+	defer fieldsLock.RUnlock()
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		names = append(names, field.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SortIssues sorts issues by the named field. A leading "-" sorts in
+// descending order.
+func SortIssues(issues []*gcode.Issue, spec string) bool {
+	descending := strings.HasPrefix(spec, "-")
+	field, ok := LookupField(strings.TrimPrefix(spec, "-"))
+	if !ok {
+		return false
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if descending {
+			// Unset values stay last
+			iSet, jSet := len(field.Values(issues[i])) > 0, len(field.Values(issues[j])) > 0
+			if iSet != jSet {
+				return iSet
+			}
+			return field.Less(issues[j], issues[i])
+		}
+		return field.Less(issues[i], issues[j])
+	})
+	return true
+}
+
+func init() {
+	RegisterField(IntProperty("id", func(entry *gcode.Issue) (int, bool) { return entry.ID, true }).Field())
+	RegisterField(StringProperty("title", func(entry *gcode.Issue) (string, bool) { return entry.Title, entry.Title != "" }).Field())
+	RegisterField(IntProperty("priority", GetIssuePriority).Field())
+	RegisterField(IntProperty("milestone", GetIssueMilestone).Field())
+	RegisterField(IntProperty("stars", GetIssueStars).Field())
+	RegisterField(StringProperty("status", GetIssueStatus).Field())
+	RegisterField(StringProperty("state", func(entry *gcode.Issue) (string, bool) { return entry.State, entry.State != "" }).Field())
+	RegisterField(StringProperty("owner", GetIssueOwner).Field())
+	RegisterField(StringProperty("reporter", func(entry *gcode.Issue) (string, bool) { return entry.Author, entry.Author != "" }).Field())
+	RegisterField(StringProperty("type", GetIssueType).Field())
+	RegisterField(StringListProperty("os", GetIssueOSList).Field())
+	RegisterField(StringListProperty("components", GetIssueCrLabels).Field())
+	RegisterField(StringListProperty("labels", GetIssueLabels).Field())
+	RegisterField(StringListProperty("cc", func(entry *gcode.Issue) []string { return entry.CCs }).Field())
+	RegisterField(TimeProperty("published", GetIssuePublished).Field())
+	RegisterField(TimeProperty("updated", GetIssueUpdated).Field())
+	RegisterField(TimeProperty("closed", GetIssueClosed).Field())
+	RegisterField(IntProperty("age", func(entry *gcode.Issue) (int, bool) { return GetIssueAge(entry, time.Now()) }).Field())
+}
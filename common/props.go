@@ -12,6 +12,16 @@ func GetIssueLabels(entry *gcode.Issue) []string {
 	return entry.Labels
 }
 
+// IssueHasLabel reports whether the issue has the label, ignoring case.
+func IssueHasLabel(entry *gcode.Issue, label string) bool {
+	for _, existing := range entry.Labels {
+		if strings.EqualFold(existing, label) {
+			return true
+		}
+	}
+	return false
+}
+
 func GetIssueLabelsByPrefix(entry *gcode.Issue, prefix string) []string {
 	filtered := make([]string, 0)
 	labels := GetIssueLabels(entry)
@@ -75,6 +85,10 @@ func GetIssueOS(entry *gcode.Issue) (string, bool) {
 	return GetIssueLabelByPrefix(entry, "OS-")
 }
 
+func GetIssueOSList(entry *gcode.Issue) []string {
+	return GetIssueLabelsByPrefix(entry, "OS-")
+}
+
 func GetIssuePublished(entry *gcode.Issue) (time.Time, bool) {
 	// 2015-02-18T00:36:15.000Z
 	// Mon Jan 2 15:04:05 -0700 MST 2006
@@ -94,3 +108,24 @@ func GetIssueUpdated(entry *gcode.Issue) (time.Time, bool) {
 	}
 	return parsed, true
 }
+
+func GetIssueClosed(entry *gcode.Issue) (time.Time, bool) {
+	parsed, err := time.Parse("2006-01-02T15:04:05.000Z", entry.Closed)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return parsed, true
+}
+
+// GetIssueAge returns the number of days an issue has been open, up to when it
+// was closed or now.
+func GetIssueAge(entry *gcode.Issue, now time.Time) (int, bool) {
+	published, ok := GetIssuePublished(entry)
+	if !ok {
+		return 0, false
+	}
+	if closed, ok := GetIssueClosed(entry); ok && entry.State == "closed" {
+		now = closed
+	}
+	return int(now.Sub(published).Hours() / 24), true
+}
@@ -1,6 +1,8 @@
 package common
 
 import (
+	"cmp"
+	"fmt"
 	"sort"
 	"strconv"
 	"time"
@@ -8,10 +10,68 @@ import (
 	"github.com/tbuckley/go-issuetracker/gcode"
 )
 
-type IntPropertyFunc func(entry *gcode.Issue) (int, bool)
-type StringPropertyFunc func(entry *gcode.Issue) (string, bool)
-type StringListPropertyFunc func(entry *gcode.Issue) []string
-type TimePropertyFunc func(entry *gcode.Issue) (time.Time, bool)
+type PropertyFunc[K any] func(entry *gcode.Issue) (K, bool)
+type ListPropertyFunc[K any] func(entry *gcode.Issue) []K
+
+type IntPropertyFunc = PropertyFunc[int]
+type StringPropertyFunc = PropertyFunc[string]
+type StringListPropertyFunc = ListPropertyFunc[string]
+type TimePropertyFunc = PropertyFunc[time.Time]
+
+// Scalar adapts a single-valued property to the multi-valued form used by the
+// grouping engine.
+func Scalar[K any](propFunc PropertyFunc[K]) ListPropertyFunc[K] {
+	return func(entry *gcode.Issue) []K {
+		val, ok := propFunc(entry)
+		if !ok {
+			return nil
+		}
+		return []K{val}
+	}
+}
+
+// Property describes how issues are keyed: Keys returns every group an issue
+// belongs to (none means the None group), Less orders keys and Format renders
+// them.
+type Property[K comparable] struct {
+	Name   string
+	Keys   ListPropertyFunc[K]
+	Less   func(a, b K) bool
+	Format func(key K) string
+	Multi  bool
+}
+
+func OrderedLess[K cmp.Ordered](a, b K) bool {
+	return a < b
+}
+
+func TimeLess(a, b time.Time) bool {
+	return a.Before(b)
+}
+
+func FormatString(s string) string {
+	return s
+}
+
+func FormatDate(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+func IntProperty(name string, propFunc IntPropertyFunc) *Property[int] {
+	return &Property[int]{Name: name, Keys: Scalar(propFunc), Less: OrderedLess[int], Format: strconv.Itoa}
+}
+
+func StringProperty(name string, propFunc StringPropertyFunc) *Property[string] {
+	return &Property[string]{Name: name, Keys: Scalar(propFunc), Less: OrderedLess[string], Format: FormatString}
+}
+
+func StringListProperty(name string, propFunc StringListPropertyFunc) *Property[string] {
+	return &Property[string]{Name: name, Keys: propFunc, Less: OrderedLess[string], Format: FormatString, Multi: true}
+}
+
+func TimeProperty(name string, propFunc TimePropertyFunc) *Property[time.Time] {
+	return &Property[time.Time]{Name: name, Keys: Scalar(propFunc), Less: TimeLess, Format: FormatDate}
+}
 
 type IssuePair interface {
 	HasKeyLessThan(p IssuePair) bool
@@ -43,236 +103,140 @@ func (l NumIssuesSortedPairList) Swap(i, j int) {
 	l[i], l[j] = l[j], l[i]
 }
 
-// INT
+// PAIRS
 
-type IntPair struct {
-	Key     *int
+type Pair[K comparable] struct {
+	Key     *K
 	Entries []*gcode.Issue
+	less    func(a, b K) bool
+	format  func(key K) string
 }
 
-func (p *IntPair) HasKeyLessThan(pair IssuePair) bool {
+func (p *Pair[K]) HasKeyLessThan(pair IssuePair) bool {
 	switch v := pair.(type) {
-	case *IntPair:
+	case *Pair[K]:
 		switch {
 		case v.Key == nil:
 			return false
 		case p.Key == nil:
 			return true
+		case p.less == nil:
+			return false
 		default:
-			return *p.Key < *v.Key
+			return p.less(*p.Key, *v.Key)
 		}
 	default:
 		return false
 	}
 }
 
-func (p *IntPair) Issues() []*gcode.Issue {
+func (p *Pair[K]) Issues() []*gcode.Issue {
 	return p.Entries
 }
 
-func (p *IntPair) KeyString() string {
+func (p *Pair[K]) KeyString() string {
 	if p.Key == nil {
 		return "None"
 	}
-	return strconv.Itoa(*p.Key)
-}
-
-type IntGroups struct {
-	Groups map[int][]*gcode.Issue
-	None   []*gcode.Issue
-}
-
-func (g *IntGroups) Pairs() []IssuePair {
-	pairs := make([]IssuePair, 0, len(g.Groups)+1)
-	for k, v := range g.Groups {
-		i := k
-		pairs = append(pairs, &IntPair{&i, v})
+	if p.format == nil {
+		return fmt.Sprint(*p.Key)
 	}
-	if len(g.None) > 0 {
-		pairs = append(pairs, &IntPair{nil, g.None})
-	}
-	return pairs
+	return p.format(*p.Key)
 }
 
-func (g *IntGroups) PairsByValue() []IssuePair {
-	pairs := g.Pairs()
-	sort.Sort(KeySortedPairList(pairs))
-	return pairs
-}
+// GROUPS
 
-func (g *IntGroups) PairsByNumEntries() []IssuePair {
-	pairs := g.Pairs()
-	sort.Sort(NumIssuesSortedPairList(pairs))
-	return pairs
-}
-
-func GroupIntProperty(entries []*gcode.Issue, propFunc IntPropertyFunc) *IntGroups {
-	groups := &IntGroups{
-		Groups: make(map[int][]*gcode.Issue),
-	}
-	for _, entry := range entries {
-		val, ok := propFunc(entry)
-		if !ok {
-			groups.None = append(groups.None, entry)
-		} else {
-			groups.Groups[val] = append(groups.Groups[val], entry)
-		}
-	}
-	return groups
-}
-
-// STRING
-
-type StringPair struct {
-	Key     *string
-	Entries []*gcode.Issue
-}
-
-func (p *StringPair) HasKeyLessThan(pair IssuePair) bool {
-	switch v := pair.(type) {
-	case *StringPair:
-		switch {
-		case v.Key == nil:
-			return false
-		case p.Key == nil:
-			return true
-		default:
-			return *p.Key < *v.Key
-		}
-	default:
-		return false
-	}
-}
-
-func (p *StringPair) Issues() []*gcode.Issue {
-	return p.Entries
+type Groups[K comparable] struct {
+	Groups map[K][]*gcode.Issue
+	None   []*gcode.Issue
+	less   func(a, b K) bool
+	format func(key K) string
 }
 
-func (p *StringPair) KeyString() string {
-	if p.Key == nil {
-		return "None"
-	}
-	return *p.Key
+func (g *Groups[K]) Pair(key K) *Pair[K] {
+	return &Pair[K]{&key, g.Groups[key], g.less, g.format}
 }
 
-type StringGroups struct {
-	Groups map[string][]*gcode.Issue
-	None   []*gcode.Issue
+func (g *Groups[K]) NonePair() *Pair[K] {
+	return &Pair[K]{nil, g.None, g.less, g.format}
 }
 
-func (g *StringGroups) Pairs() []IssuePair {
+func (g *Groups[K]) Pairs() []IssuePair {
 	pairs := make([]IssuePair, 0, len(g.Groups)+1)
-	for k, v := range g.Groups {
-		i := k
-		pairs = append(pairs, &StringPair{&i, v})
+	for k := range g.Groups {
+		pairs = append(pairs, g.Pair(k))
 	}
 	if len(g.None) > 0 {
-		pairs = append(pairs, &StringPair{nil, g.None})
+		pairs = append(pairs, g.NonePair())
 	}
 	return pairs
 }
 
-func (g *StringGroups) PairsByValue() []IssuePair {
+func (g *Groups[K]) PairsByValue() []IssuePair {
 	pairs := g.Pairs()
 	sort.Sort(KeySortedPairList(pairs))
 	return pairs
 }
 
-func (g *StringGroups) PairsByNumEntries() []IssuePair {
+func (g *Groups[K]) PairsByNumEntries() []IssuePair {
 	pairs := g.Pairs()
 	sort.Sort(NumIssuesSortedPairList(pairs))
 	return pairs
 }
 
-func GroupStringProperty(entries []*gcode.Issue, propFunc StringPropertyFunc) *StringGroups {
-	groups := &StringGroups{
-		Groups: make(map[string][]*gcode.Issue),
+// GroupBy groups entries by every key the property returns, so an issue with
+// several keys appears in several groups.
+func GroupBy[K comparable](entries []*gcode.Issue, prop *Property[K]) *Groups[K] {
+	groups := &Groups[K]{
+		Groups: make(map[K][]*gcode.Issue),
+		less:   prop.Less,
+		format: prop.Format,
 	}
 	for _, entry := range entries {
-		val, ok := propFunc(entry)
-		if !ok {
+		vals := prop.Keys(entry)
+		if len(vals) == 0 {
 			groups.None = append(groups.None, entry)
-		} else {
+			continue
+		}
+		seen := make(map[K]bool, len(vals))
+		for _, val := range vals {
+			if seen[val] {
+				continue
+			}
+			seen[val] = true
 			groups.Groups[val] = append(groups.Groups[val], entry)
 		}
 	}
 	return groups
 }
 
-// TIME
+// INT
 
-type TimePair struct {
-	Key     *time.Time
-	Entries []*gcode.Issue
-}
+type IntPair = Pair[int]
+type IntGroups = Groups[int]
 
-func (p *TimePair) HasKeyLessThan(pair IssuePair) bool {
-	switch v := pair.(type) {
-	case *TimePair:
-		switch {
-		case v.Key == nil:
-			return false
-		case p.Key == nil:
-			return true
-		default:
-			return p.Key.Before(*v.Key)
-		}
-	default:
-		return false
-	}
+func GroupIntProperty(entries []*gcode.Issue, propFunc IntPropertyFunc) *IntGroups {
+	return GroupBy(entries, IntProperty("", propFunc))
 }
 
-func (p *TimePair) Issues() []*gcode.Issue {
-	return p.Entries
-}
+// STRING
 
-func (p *TimePair) KeyString() string {
-	if p.Key == nil {
-		return "None"
-	}
-	return p.Key.Format("2006-01-02")
-}
+type StringPair = Pair[string]
+type StringGroups = Groups[string]
 
-type TimeGroups struct {
-	Groups map[time.Time][]*gcode.Issue
-	None   []*gcode.Issue
+func GroupStringProperty(entries []*gcode.Issue, propFunc StringPropertyFunc) *StringGroups {
+	return GroupBy(entries, StringProperty("", propFunc))
 }
 
-func (g *TimeGroups) Pairs() []IssuePair {
-	pairs := make([]IssuePair, 0, len(g.Groups)+1)
-	for k, v := range g.Groups {
-		i := k
-		pairs = append(pairs, &TimePair{&i, v})
-	}
-	if len(g.None) > 0 {
-		pairs = append(pairs, &TimePair{nil, g.None})
-	}
-	return pairs
+func GroupStringListProperty(entries []*gcode.Issue, propFunc StringListPropertyFunc) *StringGroups {
+	return GroupBy(entries, StringListProperty("", propFunc))
 }
 
-func (g *TimeGroups) PairsByValue() []IssuePair {
-	pairs := g.Pairs()
-	sort.Sort(KeySortedPairList(pairs))
-	return pairs
-}
+// TIME
 
-func (g *TimeGroups) PairsByNumEntries() []IssuePair {
-	pairs := g.Pairs()
-	sort.Sort(NumIssuesSortedPairList(pairs))
-	return pairs
-}
+type TimePair = Pair[time.Time]
+type TimeGroups = Groups[time.Time]
 
 func GroupTimeProperty(entries []*gcode.Issue, propFunc TimePropertyFunc) *TimeGroups {
-	groups := &TimeGroups{
-		Groups: make(map[time.Time][]*gcode.Issue),
-	}
-	for _, entry := range entries {
-		val, ok := propFunc(entry)
-		if !ok {
-			groups.None = append(groups.None, entry)
-		} else {
-			groups.Groups[val] = append(groups.Groups[val], entry)
-		}
-	}
-	return groups
+	return GroupBy(entries, TimeProperty("", propFunc))
 }
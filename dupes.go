@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/tbuckley/go-issuetracker/dupes"
+	"github.com/tbuckley/go-issuetracker/query"
+)
+
+func runDupes(wg *query.WorkGroup, client *http.Client, args []string) {
+	flags := flag.NewFlagSet("dupes", flag.ExitOnError)
+	project := flags.String("project", "chromium", "Project to check")
+	q := flags.String("query", "Cr:UI", "Search query selecting the open issues to compare")
+	id := flags.Int("id", 0, "Only find duplicates of this issue")
+	minScore := flags.Float64("min-score", 0.3, "Minimum total score of a candidate")
+	limit := flags.Int("limit", 20, "Maximum number of candidates")
+	flags.Parse(args)
+
+	issues, err := query.CollectIssues(wg.NewQuery(*project).Client(client).Query(*q).FetchAllIssues())
+	if err != nil {
+		fmt.Printf("Error: %v\n", err.Error())
+		return
+	}
+
+	detector, err := dupes.New(issues, dupes.DefaultWeights)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err.Error())
+		return
+	}
+	var candidates []*dupes.Candidate
+	if *id != 0 {
+		found := false
+		for _, issue := range issues {
+			if issue.ID == *id {
+				candidates = detector.CandidatesFor(issue, *minScore, *limit)
+				found = true
+				break
+			}
+		}
+		if !found {
+			fmt.Printf("Issue %v is not among the %v open issues matching %q\n", *id, len(issues), *q)
+			return
+		}
+	} else {
+		candidates = detector.Candidates(*minScore, *limit)
+	}
+
+	fmt.Printf("%v candidates among %v issues\n", len(candidates), len(issues))
+	dupes.WriteCandidates(os.Stdout, candidates)
+}
@@ -0,0 +1,262 @@
+package dupes
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tbuckley/go-issuetracker/common"
+	"github.com/tbuckley/go-issuetracker/gcode"
+	"github.com/tbuckley/go-issuetracker/search"
+)
+
+// Weights controls how much each signal contributes to a candidate's total
+// score. Time similarity halves every TimeScale between the two issues being
+// opened.
+type Weights struct {
+	Text      float64
+	Labels    float64
+	Time      float64
+	TimeScale time.Duration
+}
+
+var DefaultWeights = Weights{
+	Text:      0.7,
+	Labels:    0.2,
+	Time:      0.1,
+	TimeScale: 14 * 24 * time.Hour,
+}
+
+// Score is the breakdown of a candidate pair. Each signal is between 0 and 1.
+type Score struct {
+	Text   float64 `json:"text"`
+	Labels float64 `json:"labels"`
+	Time   float64 `json:"time"`
+	Total  float64 `json:"total"`
+}
+
+type Candidate struct {
+	Issue     *gcode.Issue `json:"issue"`
+	Duplicate *gcode.Issue `json:"duplicate"`
+	Score     Score        `json:"score"`
+}
+
+// Validate returns an error if any weight is negative or they are all zero,
+// as the total score is their weighted average.
+func (w Weights) Validate() error {
+	if w.Text < 0 || w.Labels < 0 || w.Time < 0 {
+		return fmt.Errorf("Invalid weights, must not be negative: %+v", w)
+	}
+	if w.Text+w.Labels+w.Time <= 0 {
+		return fmt.Errorf("Invalid weights, must not all be zero: %+v", w)
+	}
+	return nil
+}
+
+type vector map[string]float64
+
+// Detector holds the TF-IDF vectors of a set of issues.
+type Detector struct {
+	Weights Weights
+	issues  []*gcode.Issue
+	vectors map[int]vector
+	docFreq map[string]int
+}
+
+func tokens(issue *gcode.Issue) []string {
+	// The title is repeated as it says more about the bug than the content
+	text := issue.Title + " " + issue.Title + " " + issue.Content
+	return search.Tokenize(text)
+}
+
+func New(issues []*gcode.Issue, weights Weights) (*Detector, error) {
+	if err := weights.Validate(); err != nil {
+		return nil, err
+	}
+
+	docFreq := make(map[string]int)
+	counts := make(map[int]map[string]int, len(issues))
+	for _, issue := range issues {
+		count := termCounts(issue)
+		for token := range count {
+			docFreq[token]++
+		}
+		counts[issue.ID] = count
+	}
+
+	d := &Detector{Weights: weights, issues: issues, vectors: make(map[int]vector, len(issues)), docFreq: docFreq}
+	for id, count := range counts {
+		d.vectors[id] = d.vectorize(count)
+	}
+	return d, nil
+}
+
+func termCounts(issue *gcode.Issue) map[string]int {
+	count := make(map[string]int)
+	for _, token := range tokens(issue) {
+		count[token]++
+	}
+	return count
+}
+
+// vectorize weighs the term counts of a document by the IDF of the set.
+// Terms that don't appear in the set are treated as appearing once.
+func (d *Detector) vectorize(count map[string]int) vector {
+	total := float64(len(d.issues))
+	v := make(vector, len(count))
+	norm := 0.0
+	for token, n := range count {
+		df := d.docFreq[token]
+		if df == 0 {
+			df = 1
+		}
+		weight := (1 + math.Log(float64(n))) * math.Log(1+total/float64(df))
+		v[token] = weight
+		norm += weight * weight
+	}
+	if norm == 0 {
+		return v
+	}
+	norm = math.Sqrt(norm)
+	for token := range v {
+		v[token] /= norm
+	}
+	return v
+}
+
+func cosine(a, b vector) float64 {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	sum := 0.0
+	for token, weight := range a {
+		sum += weight * b[token]
+	}
+	return sum
+}
+
+// labelSimilarity is the Jaccard index of the labels, ignoring priority and
+// milestone labels which are shared by too many unrelated issues.
+func labelSimilarity(a, b *gcode.Issue) float64 {
+	relevant := func(issue *gcode.Issue) map[string]bool {
+		labels := make(map[string]bool)
+		for _, label := range issue.Labels {
+			lower := strings.ToLower(label)
+			if strings.HasPrefix(lower, "pri-") || strings.HasPrefix(lower, "m-") {
+				continue
+			}
+			labels[lower] = true
+		}
+		return labels
+	}
+	aLabels, bLabels := relevant(a), relevant(b)
+	if len(aLabels) == 0 && len(bLabels) == 0 {
+		return 0
+	}
+	shared := 0
+	for label := range aLabels {
+		if bLabels[label] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(aLabels)+len(bLabels)-shared)
+}
+
+func (d *Detector) timeSimilarity(a, b *gcode.Issue) float64 {
+	aTime, aOk := common.GetIssuePublished(a)
+	bTime, bOk := common.GetIssuePublished(b)
+	if !aOk || !bOk || d.Weights.TimeScale <= 0 {
+		return 0
+	}
+	gap := math.Abs(float64(aTime.Sub(bTime)))
+	return math.Pow(0.5, gap/float64(d.Weights.TimeScale))
+}
+
+// vector returns the TF-IDF vector of an issue, computing it against the
+// set's IDF if the issue isn't part of the set.
+func (d *Detector) vector(issue *gcode.Issue) vector {
+	if v, ok := d.vectors[issue.ID]; ok {
+		return v
+	}
+	return d.vectorize(termCounts(issue))
+}
+
+func (d *Detector) Score(a, b *gcode.Issue) Score {
+	s := Score{
+		Text:   cosine(d.vector(a), d.vector(b)),
+		Labels: labelSimilarity(a, b),
+		Time:   d.timeSimilarity(a, b),
+	}
+	w := d.Weights
+	if sum := w.Text + w.Labels + w.Time; sum > 0 {
+		s.Total = (w.Text*s.Text + w.Labels*s.Labels + w.Time*s.Time) / sum
+	}
+	return s
+}
+
+func sortCandidates(candidates []*Candidate, limit int) []*Candidate {
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score.Total != candidates[j].Score.Total {
+			return candidates[i].Score.Total > candidates[j].Score.Total
+		}
+		if candidates[i].Issue.ID != candidates[j].Issue.ID {
+			return candidates[i].Issue.ID < candidates[j].Issue.ID
+		}
+		return candidates[i].Duplicate.ID < candidates[j].Duplicate.ID
+	})
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates
+}
+
+// CandidatesFor returns the issues most likely to be duplicates of issue,
+// scoring at least minScore.
+func (d *Detector) CandidatesFor(issue *gcode.Issue, minScore float64, limit int) []*Candidate {
+	candidates := make([]*Candidate, 0)
+	for _, other := range d.issues {
+		if other.ID == issue.ID {
+			continue
+		}
+		score := d.Score(issue, other)
+		if score.Total >= minScore {
+			candidates = append(candidates, &Candidate{Issue: issue, Duplicate: other, Score: score})
+		}
+	}
+	return sortCandidates(candidates, limit)
+}
+
+// Candidates returns the most likely duplicate pairs in the whole set. Each
+// pair is reported once, with the older issue first.
+func (d *Detector) Candidates(minScore float64, limit int) []*Candidate {
+	candidates := make([]*Candidate, 0)
+	for i, a := range d.issues {
+		for _, b := range d.issues[i+1:] {
+			score := d.Score(a, b)
+			if score.Total < minScore {
+				continue
+			}
+			older, newer := a, b
+			if newer.ID < older.ID {
+				older, newer = newer, older
+			}
+			candidates = append(candidates, &Candidate{Issue: older, Duplicate: newer, Score: score})
+		}
+	}
+	return sortCandidates(candidates, limit)
+}
+
+func WriteCandidates(w io.Writer, candidates []*Candidate) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "Score\tText\tLabels\tTime\tIssue\tPossible duplicate")
+	for _, c := range candidates {
+		fmt.Fprintf(tw, "%.2f\t%.2f\t%.2f\t%.2f\tcrbug.com/%v %v\tcrbug.com/%v %v\n",
+			c.Score.Total, c.Score.Text, c.Score.Labels, c.Score.Time,
+			c.Issue.ID, c.Issue.Title, c.Duplicate.ID, c.Duplicate.Title)
+	}
+	return tw.Flush()
+}
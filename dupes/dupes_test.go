@@ -0,0 +1,49 @@
+package dupes
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tbuckley/go-issuetracker/gcode"
+)
+
+func testIssues() []*gcode.Issue {
+	return []*gcode.Issue{
+		{ID: 1, Entry: gcode.Entry{Title: "Crash when opening settings", Content: "The browser crashes on the settings page"}},
+		{ID: 2, Entry: gcode.Entry{Title: "Settings page crash", Content: "Opening settings crashes the browser"}},
+		{ID: 3, Entry: gcode.Entry{Title: "Bookmarks bar is missing", Content: "The bookmarks bar disappeared after update"}},
+	}
+}
+
+func TestNewRejectsInvalidWeights(t *testing.T) {
+	for _, weights := range []Weights{
+		{},
+		{Text: 1, Labels: -1},
+	} {
+		if _, err := New(testIssues(), weights); err == nil {
+			t.Errorf("New(%+v) succeeded, want an error", weights)
+		}
+	}
+}
+
+func TestScoreOutsideIssueUsesSetIDF(t *testing.T) {
+	issues := testIssues()
+	d, err := New(issues, DefaultWeights)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A copy of an issue in the set must score exactly like the original.
+	outside := *issues[0]
+	outside.ID = 100
+	for _, other := range issues[1:] {
+		want := d.Score(issues[0], other)
+		got := d.Score(&outside, other)
+		if math.IsNaN(got.Total) || math.Abs(got.Text-want.Text) > 1e-9 {
+			t.Errorf("Score(outside, %v).Text = %v, want %v", other.ID, got.Text, want.Text)
+		}
+	}
+	if got := d.Score(&outside, issues[0]); math.Abs(got.Text-1) > 1e-9 {
+		t.Errorf("Score(outside, original).Text = %v, want 1", got.Text)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/tbuckley/go-issuetracker/export"
+	"github.com/tbuckley/go-issuetracker/query"
+)
+
+func runExport(wg *query.WorkGroup, client *http.Client, args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	project := flags.String("project", "chromium", "Project to export")
+	q := flags.String("query", "", "Search query selecting the issues")
+	label := flags.String("label", "", "Label selecting the issues")
+	all := flags.Bool("all", false, "Export closed issues too")
+	format := flags.String("format", "jsonl", "Output format: jsonl or csv")
+	columns := flags.String("columns", "", "Comma-separated fields, e.g. id,priority,age,label:Cr-")
+	replies := flags.Bool("replies", false, "Include replies")
+	out := flags.String("out", "", "Output file (default stdout)")
+	flags.Parse(args)
+
+	fetch := wg.NewQuery(*project).Client(client)
+	if *q != "" {
+		fetch = fetch.Query(*q)
+	}
+	if *label != "" {
+		fetch = fetch.Label(*label)
+	}
+	if *all {
+		fetch = fetch.All()
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err.Error())
+			return
+		}
+		defer f.Close()
+		w = f
+	}
+
+	var issueChan chan query.OptionalIssue
+	if *replies {
+		issueChan = fetch.FetchAllIssuesWithReplies()
+	} else {
+		issueChan = fetch.FetchAllIssues()
+	}
+	count, err := export.Export(w, issueChan, export.Options{
+		Format:  export.Format(*format),
+		Columns: export.ParseColumns(*columns),
+		Replies: *replies,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err.Error())
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Exported %v issues\n", count)
+}
@@ -0,0 +1,236 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/tbuckley/go-issuetracker/common"
+	"github.com/tbuckley/go-issuetracker/gcode"
+	"github.com/tbuckley/go-issuetracker/query"
+)
+
+var (
+	UnknownFormat = errors.New("Unknown export format")
+)
+
+type Format string
+
+const (
+	JSONL Format = "jsonl"
+	CSV   Format = "csv"
+)
+
+// DefaultSeparator joins multi-valued fields in CSV exports.
+const DefaultSeparator = ";"
+
+// DefaultColumns are used for CSV exports when no columns are given.
+var DefaultColumns = []string{"id", "title", "status", "priority", "milestone", "owner", "labels"}
+
+// Options configure an export. Columns name registered fields (see
+// common.LookupField), including "label:Prefix-" columns. A JSONL export
+// without columns writes whole issues. Multi-valued fields are written as
+// arrays in JSONL and joined with Separator in CSV.
+type Options struct {
+	Format    Format
+	Columns   []string
+	Replies   bool
+	Separator string
+}
+
+type Writer interface {
+	Write(issue *gcode.Issue) error
+	Close() error
+}
+
+func lookupColumns(names []string) ([]*common.Field, error) {
+	columns := make([]*common.Field, len(names))
+	for i, name := range names {
+		field, ok := common.LookupField(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("Unknown column: %v", name)
+		}
+		columns[i] = field
+	}
+	return columns, nil
+}
+
+// ParseColumns splits a comma-separated column spec such as
+// "id,priority,label:Cr-".
+func ParseColumns(spec string) []string {
+	if strings.TrimSpace(spec) == "" {
+		return nil
+	}
+	return strings.Split(spec, ",")
+}
+
+func NewWriter(w io.Writer, opts Options) (Writer, error) {
+	names := opts.Columns
+	if opts.Format == CSV && len(names) == 0 {
+		names = DefaultColumns
+	}
+	columns, err := lookupColumns(names)
+	if err != nil {
+		return nil, err
+	}
+
+	switch opts.Format {
+	case JSONL:
+		return &jsonlWriter{encoder: json.NewEncoder(w), columns: columns, replies: opts.Replies}, nil
+	case CSV:
+		separator := opts.Separator
+		if separator == "" {
+			separator = DefaultSeparator
+		}
+		return &csvWriter{writer: csv.NewWriter(w), columns: columns, replies: opts.Replies, separator: separator}, nil
+	}
+	return nil, UnknownFormat
+}
+
+// Export streams issues from issueChan to w, returning the number written.
+// The channel is always drained.
+func Export(w io.Writer, issueChan chan query.OptionalIssue, opts Options) (int, error) {
+	writer, err := NewWriter(w, opts)
+	if err != nil {
+		for range issueChan {
+		}
+		return 0, err
+	}
+
+	count := 0
+	for optionalIssue := range issueChan {
+		if err != nil {
+			continue
+		}
+		if optionalIssue.Error != nil {
+			err = optionalIssue.Error
+			continue
+		}
+		if err = writer.Write(optionalIssue.Issue); err == nil {
+			count++
+		}
+	}
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	return count, err
+}
+
+type Reply struct {
+	Author    string   `json:"author"`
+	Published string   `json:"published"`
+	Content   string   `json:"content"`
+	Status    string   `json:"status,omitempty"`
+	Owner     string   `json:"owner,omitempty"`
+	Labels    []string `json:"labels,omitempty"`
+	CCs       []string `json:"cc,omitempty"`
+}
+
+func replies(issue *gcode.Issue) []*Reply {
+	result := make([]*Reply, len(issue.Replies))
+	for i, reply := range issue.Replies {
+		result[i] = &Reply{
+			Author:    reply.Author,
+			Published: reply.Published,
+			Content:   reply.Content,
+			Status:    reply.StatusChange,
+			Owner:     reply.OwnerChange,
+			Labels:    reply.LabelChanges,
+			CCs:       reply.CCChanges,
+		}
+	}
+	return result
+}
+
+type jsonlWriter struct {
+	encoder *json.Encoder
+	columns []*common.Field
+	replies bool
+}
+
+func (w *jsonlWriter) Write(issue *gcode.Issue) error {
+	if len(w.columns) == 0 {
+		if !w.replies && len(issue.Replies) > 0 {
+			stripped := *issue
+			stripped.Replies = nil
+			issue = &stripped
+		}
+		return w.encoder.Encode(issue)
+	}
+
+	row := make(map[string]interface{}, len(w.columns)+1)
+	for _, column := range w.columns {
+		values := column.Values(issue)
+		switch {
+		case column.Multi:
+			row[column.Name] = values
+		case len(values) == 0:
+			row[column.Name] = nil
+		default:
+			row[column.Name] = values[0]
+		}
+	}
+	if w.replies {
+		row["replies"] = replies(issue)
+	}
+	return w.encoder.Encode(row)
+}
+
+func (w *jsonlWriter) Close() error {
+	return nil
+}
+
+type csvWriter struct {
+	writer    *csv.Writer
+	columns   []*common.Field
+	replies   bool
+	separator string
+	started   bool
+}
+
+func (w *csvWriter) writeHeader() error {
+	w.started = true
+	header := make([]string, 0, len(w.columns)+1)
+	for _, column := range w.columns {
+		header = append(header, column.Name)
+	}
+	if w.replies {
+		header = append(header, "replies")
+	}
+	return w.writer.Write(header)
+}
+
+func (w *csvWriter) Write(issue *gcode.Issue) error {
+	if !w.started {
+		if err := w.writeHeader(); err != nil {
+			return err
+		}
+	}
+
+	record := make([]string, 0, len(w.columns)+1)
+	for _, column := range w.columns {
+		record = append(record, strings.Join(column.Values(issue), w.separator))
+	}
+	if w.replies {
+		// Replies don't fit in a column, so they share one cell, one per line
+		lines := make([]string, len(issue.Replies))
+		for i, reply := range issue.Replies {
+			lines[i] = fmt.Sprintf("%v (%v): %v", reply.Author, reply.Published, strings.TrimSpace(reply.Content))
+		}
+		record = append(record, strings.Join(lines, "\n"))
+	}
+	return w.writer.Write(record)
+}
+
+func (w *csvWriter) Close() error {
+	if !w.started {
+		if err := w.writeHeader(); err != nil {
+			return err
+		}
+	}
+	w.writer.Flush()
+	return w.writer.Error()
+}
@@ -0,0 +1,142 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tbuckley/go-issuetracker/gcode"
+	"github.com/tbuckley/go-issuetracker/query"
+)
+
+func exportIssue() *gcode.Issue {
+	issue := &gcode.Issue{
+		ID:     12,
+		Status: "Assigned",
+		Owner:  "a@chromium.org",
+		Labels: []string{"Pri-1", "Cr-UI", "Cr-UI-Settings", "OS-Mac"},
+	}
+	issue.Title = "Settings, crash"
+	reply := &gcode.Reply{}
+	reply.Author = "b@chromium.org"
+	reply.Content = "Confirmed"
+	issue.Replies = []*gcode.Reply{reply}
+	return issue
+}
+
+func issueChan(issues ...*gcode.Issue) chan query.OptionalIssue {
+	c := make(chan query.OptionalIssue, len(issues))
+	for _, issue := range issues {
+		c <- query.OptionalIssue{Issue: issue}
+	}
+	close(c)
+	return c
+}
+
+func TestCSVColumns(t *testing.T) {
+	buf := new(bytes.Buffer)
+	opts := Options{Format: CSV, Columns: ParseColumns("id,title,priority,components,label:Cr-,os")}
+	count, err := Export(buf, issueChan(exportIssue()), opts)
+	if err != nil || count != 1 {
+		t.Fatalf("Export = %v, %v", count, err)
+	}
+	want := "id,title,priority,components,label:Cr-,os\n" +
+		"12,\"Settings, crash\",1,UI;UI-Settings,UI;UI-Settings,Mac\n"
+	if buf.String() != want {
+		t.Errorf("got\n%v\nwant\n%v", buf.String(), want)
+	}
+}
+
+func TestCSVHeaderOnEmptyInput(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if _, err := Export(buf, issueChan(), Options{Format: CSV}); err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Join(DefaultColumns, ",") + "\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestCSVSeparatorAndReplies(t *testing.T) {
+	buf := new(bytes.Buffer)
+	opts := Options{Format: CSV, Columns: []string{"id", "labels"}, Separator: "|", Replies: true}
+	if _, err := Export(buf, issueChan(exportIssue()), opts); err != nil {
+		t.Fatal(err)
+	}
+	want := "id,labels,replies\n12,Pri-1|Cr-UI|Cr-UI-Settings|OS-Mac,b@chromium.org (): Confirmed\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestJSONLColumns(t *testing.T) {
+	buf := new(bytes.Buffer)
+	opts := Options{Format: JSONL, Columns: []string{"id", "milestone", "components"}}
+	if _, err := Export(buf, issueChan(exportIssue()), opts); err != nil {
+		t.Fatal(err)
+	}
+	row := make(map[string]interface{})
+	if err := json.Unmarshal(buf.Bytes(), &row); err != nil {
+		t.Fatal(err)
+	}
+	if row["id"] != "12" {
+		t.Errorf("id = %#v, want \"12\"", row["id"])
+	}
+	if value, ok := row["milestone"]; !ok || value != nil {
+		t.Errorf("milestone = %#v, want null", value)
+	}
+	components, ok := row["components"].([]interface{})
+	if !ok || len(components) != 2 || components[0] != "UI" || components[1] != "UI-Settings" {
+		t.Errorf("components = %#v, want [UI UI-Settings]", row["components"])
+	}
+}
+
+func TestJSONLWholeIssues(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if _, err := Export(buf, issueChan(exportIssue(), exportIssue()), Options{Format: JSONL}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %v lines, want 2", len(lines))
+	}
+	issue := new(gcode.Issue)
+	if err := json.Unmarshal([]byte(lines[0]), issue); err != nil {
+		t.Fatal(err)
+	}
+	if issue.ID != 12 || issue.Title != "Settings, crash" || len(issue.Replies) != 0 {
+		t.Errorf("got issue %v %q with %v replies", issue.ID, issue.Title, len(issue.Replies))
+	}
+}
+
+func TestExportErrors(t *testing.T) {
+	if _, err := NewWriter(new(bytes.Buffer), Options{Format: "xml"}); err != UnknownFormat {
+		t.Errorf("unknown format error = %v", err)
+	}
+	if _, err := NewWriter(new(bytes.Buffer), Options{Format: CSV, Columns: []string{"nope"}}); err == nil {
+		t.Errorf("unknown column accepted")
+	}
+
+	failed := errors.New("fetch failed")
+	c := make(chan query.OptionalIssue, 2)
+	c <- query.OptionalIssue{Issue: exportIssue()}
+	c <- query.OptionalIssue{Error: failed}
+	close(c)
+	if count, err := Export(new(bytes.Buffer), c, Options{Format: CSV}); count != 1 || err != failed {
+		t.Errorf("Export = %v, %v; want 1, %v", count, err, failed)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestCSVCloseReportsHeaderError(t *testing.T) {
+	if _, err := Export(failingWriter{}, issueChan(), Options{Format: CSV}); err == nil {
+		t.Errorf("Export to a failing writer succeeded")
+	}
+}
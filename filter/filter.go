@@ -0,0 +1,297 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/tbuckley/go-issuetracker/common"
+	"github.com/tbuckley/go-issuetracker/gcode"
+)
+
+var (
+	UnterminatedQuote = errors.New("Unterminated quote in filter")
+	EmptyGroup        = errors.New("Empty OR group in filter")
+)
+
+// term matches a single search term, e.g. "status:Untriaged" or "-has:owner".
+type term func(issue *gcode.Issue) bool
+
+// Filter is a parsed search expression using the tracker's search syntax: a
+// list of terms that must all match, optionally separated by OR. Supported
+// terms are label:, status:, owner:, reporter:, cc:, id: (with a list such as
+// id:1,2,3), is:open, is:closed, has:, opened-/closed-/updated- before/after
+// dates, Key:Value or Key=Value label prefixes such as Cr:UI or Pri=1, and
+// free text or quoted phrases matched against the title, content and replies.
+// A leading "-" negates a term.
+type Filter struct {
+	Expression string
+	anyOf      [][]term
+}
+
+func tokenize(expr string) ([]string, error) {
+	tokens := make([]string, 0)
+	current := new(strings.Builder)
+	inQuote := false
+	for _, r := range expr {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			current.WriteRune(r)
+		case unicode.IsSpace(r) && !inQuote:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if inQuote {
+		return nil, UnterminatedQuote
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+	return tokens, nil
+}
+
+func Parse(expr string) (*Filter, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Filter{Expression: expr, anyOf: make([][]term, 0)}
+	allOf := make([]term, 0)
+	for _, token := range tokens {
+		if token == "OR" {
+			// An empty group would match every issue
+			if len(allOf) == 0 {
+				return nil, EmptyGroup
+			}
+			f.anyOf = append(f.anyOf, allOf)
+			allOf = make([]term, 0)
+			continue
+		}
+		t, err := parseTerm(token)
+		if err != nil {
+			return nil, err
+		}
+		allOf = append(allOf, t)
+	}
+	if len(allOf) == 0 && len(f.anyOf) > 0 {
+		return nil, EmptyGroup
+	}
+	f.anyOf = append(f.anyOf, allOf)
+	return f, nil
+}
+
+func MustParse(expr string) *Filter {
+	f, err := Parse(expr)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func (f *Filter) Match(issue *gcode.Issue) bool {
+	for _, allOf := range f.anyOf {
+		matched := true
+		for _, t := range allOf {
+			if !t(issue) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Filter) Apply(issues []*gcode.Issue) []*gcode.Issue {
+	matched := make([]*gcode.Issue, 0)
+	for _, issue := range issues {
+		if f.Match(issue) {
+			matched = append(matched, issue)
+		}
+	}
+	return matched
+}
+
+func parseTerm(token string) (term, error) {
+	if strings.HasPrefix(token, "-") && len(token) > 1 {
+		t, err := parseTerm(token[1:])
+		if err != nil {
+			return nil, err
+		}
+		return func(issue *gcode.Issue) bool { return !t(issue) }, nil
+	}
+
+	if strings.HasPrefix(token, "\"") {
+		return textTerm(strings.Trim(token, "\"")), nil
+	}
+
+	key, value, ok := splitTerm(token)
+	if !ok {
+		return textTerm(token), nil
+	}
+	value = strings.Trim(value, "\"")
+
+	switch strings.ToLower(key) {
+	case "label":
+		return labelTerm(value), nil
+	case "status":
+		return stringTerm(value, func(issue *gcode.Issue) string { return issue.Status }), nil
+	case "owner":
+		return stringTerm(value, func(issue *gcode.Issue) string { return issue.Owner }), nil
+	case "reporter":
+		return stringTerm(value, func(issue *gcode.Issue) string { return issue.Author }), nil
+	case "cc":
+		return func(issue *gcode.Issue) bool {
+			for _, cc := range issue.CCs {
+				if strings.EqualFold(cc, value) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case "id":
+		ids := make(map[int]bool)
+		for _, part := range strings.Split(value, ",") {
+			id, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid id in filter: %v", value)
+			}
+			ids[id] = true
+		}
+		return func(issue *gcode.Issue) bool { return ids[issue.ID] }, nil
+	case "is":
+		return isTerm(value)
+	case "has":
+		return hasTerm(value), nil
+	case "opened-before", "opened-after", "closed-before", "closed-after", "updated-before", "updated-after":
+		return dateTerm(strings.ToLower(key), value)
+	}
+	return labelPrefixTerm(key + "-" + value), nil
+}
+
+// splitTerm splits "key:value" and "key=value", leaving plain words alone.
+func splitTerm(token string) (string, string, bool) {
+	i := strings.IndexAny(token, ":=")
+	if i <= 0 || i == len(token)-1 || strings.HasPrefix(token, "\"") {
+		return "", "", false
+	}
+	return token[:i], token[i+1:], true
+}
+
+func stringTerm(value string, get func(issue *gcode.Issue) string) term {
+	return func(issue *gcode.Issue) bool {
+		return strings.EqualFold(get(issue), value)
+	}
+}
+
+func labelTerm(label string) term {
+	return func(issue *gcode.Issue) bool {
+		return common.IssueHasLabel(issue, label)
+	}
+}
+
+// labelPrefixTerm matches the label itself and its children, so Cr-UI matches
+// Cr-UI-Settings.
+func labelPrefixTerm(label string) term {
+	lower := strings.ToLower(label)
+	return func(issue *gcode.Issue) bool {
+		for _, existing := range issue.Labels {
+			existing = strings.ToLower(existing)
+			if existing == lower || strings.HasPrefix(existing, lower+"-") {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func isTerm(value string) (term, error) {
+	switch strings.ToLower(value) {
+	case "open":
+		return func(issue *gcode.Issue) bool { return issue.State != "closed" }, nil
+	case "closed":
+		return func(issue *gcode.Issue) bool { return issue.State == "closed" }, nil
+	}
+	return nil, fmt.Errorf("Unknown filter term: is:%v", value)
+}
+
+func hasTerm(value string) term {
+	switch strings.ToLower(value) {
+	case "owner":
+		return func(issue *gcode.Issue) bool { return issue.Owner != "" }
+	case "status":
+		return func(issue *gcode.Issue) bool { return issue.Status != "" }
+	case "cc":
+		return func(issue *gcode.Issue) bool { return len(issue.CCs) > 0 }
+	case "blockedon":
+		return func(issue *gcode.Issue) bool { return len(issue.BlockedOn) > 0 }
+	case "blocking":
+		return func(issue *gcode.Issue) bool { return len(issue.Blocking) > 0 }
+	}
+	prefix := value + "-"
+	return func(issue *gcode.Issue) bool {
+		for _, label := range issue.Labels {
+			if len(label) > len(prefix) && strings.EqualFold(label[:len(prefix)], prefix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func dateTerm(key string, value string) (term, error) {
+	date, err := time.Parse("2006/01/02", value)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid date in filter: %v", value)
+	}
+
+	var get common.TimePropertyFunc
+	switch {
+	case strings.HasPrefix(key, "opened"):
+		get = common.GetIssuePublished
+	case strings.HasPrefix(key, "closed"):
+		get = common.GetIssueClosed
+	default:
+		get = common.GetIssueUpdated
+	}
+	before := strings.HasSuffix(key, "-before")
+
+	return func(issue *gcode.Issue) bool {
+		t, ok := get(issue)
+		if !ok {
+			return false
+		}
+		if before {
+			return t.Before(date)
+		}
+		return !t.Before(date.AddDate(0, 0, 1))
+	}, nil
+}
+
+func textTerm(text string) term {
+	lower := strings.ToLower(text)
+	return func(issue *gcode.Issue) bool {
+		if strings.Contains(strings.ToLower(issue.Title), lower) ||
+			strings.Contains(strings.ToLower(issue.Content), lower) {
+			return true
+		}
+		for _, reply := range issue.Replies {
+			if strings.Contains(strings.ToLower(reply.Content), lower) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,79 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/tbuckley/go-issuetracker/gcode"
+)
+
+func filterIssue(id int, state, status, owner string, labels ...string) *gcode.Issue {
+	issue := &gcode.Issue{ID: id, State: state, Status: status, Owner: owner, Labels: labels}
+	issue.Title = "Crash in settings"
+	issue.Published = "2015-02-10T12:00:00.000Z"
+	return issue
+}
+
+func TestParseMatch(t *testing.T) {
+	issues := []*gcode.Issue{
+		filterIssue(1, "open", "Untriaged", "", "Cr-UI", "Pri-1"),
+		filterIssue(2, "open", "Assigned", "a@chromium.org", "Cr-UI-Settings", "Pri-2"),
+		filterIssue(3, "closed", "Fixed", "b@chromium.org", "Cr-Blink"),
+	}
+	tests := []struct {
+		expr string
+		want []int
+	}{
+		{"", []int{1, 2, 3}},
+		{"status:Untriaged", []int{1}},
+		{"-has:owner", []int{1}},
+		{"Cr:UI", []int{1, 2}},
+		{"label:Cr-UI", []int{1}},
+		{"Pri=2 OR is:closed", []int{2, 3}},
+		{"owner:a@chromium.org Cr:UI", []int{2}},
+		{"id:1,3", []int{1, 3}},
+		{`"in settings" -is:closed`, []int{1, 2}},
+		{"opened-after:2015/02/09 opened-before:2015/02/11", []int{1, 2, 3}},
+		{"opened-after:2015/02/10", []int{}},
+	}
+	for _, test := range tests {
+		f, err := Parse(test.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.expr, err)
+			continue
+		}
+		got := make([]int, 0)
+		for _, issue := range f.Apply(issues) {
+			got = append(got, issue.ID)
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("%q matched %v, want %v", test.expr, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%q matched %v, want %v", test.expr, got, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]error{
+		"a OR":                   EmptyGroup,
+		"OR b":                   EmptyGroup,
+		"a OR OR b":              EmptyGroup,
+		`"unfinished`:            UnterminatedQuote,
+		"is:pending":             nil,
+		"id:1,x":                 nil,
+		"opened-after:yesterday": nil,
+	}
+	for expr, want := range tests {
+		_, err := Parse(expr)
+		if err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", expr)
+		} else if want != nil && err != want {
+			t.Errorf("Parse(%q) = %v, want %v", expr, err, want)
+		}
+	}
+}
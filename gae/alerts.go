@@ -0,0 +1,119 @@
+package gae
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"time"
+
+	"appengine"
+	"appengine/datastore"
+	"appengine/mail"
+	"appengine/urlfetch"
+
+	"github.com/tbuckley/go-issuetracker/alerts"
+	"github.com/tbuckley/go-issuetracker/analytics"
+	"github.com/tbuckley/go-issuetracker/gcode"
+)
+
+const (
+	alertsConfigFile = "alerts.json"
+	currentMilestone = 42
+)
+
+type AlertStateEntry struct {
+	Data []byte `datastore:",noindex"`
+}
+
+func getAlertStateKey(ctx appengine.Context) *datastore.Key {
+	return datastore.NewKey(ctx, "AlertState", "alerts", 0, nil)
+}
+
+func GetAlertState(ctx appengine.Context) (*alerts.State, error) {
+	entry := new(AlertStateEntry)
+	err := datastore.Get(ctx, getAlertStateKey(ctx), entry)
+	if err == datastore.ErrNoSuchEntity {
+		return alerts.NewState(), nil
+	} else if err != nil {
+		return nil, err
+	}
+	state := alerts.NewState()
+	err = json.Unmarshal(entry.Data, state)
+	return state, err
+}
+
+func SetAlertState(ctx appengine.Context, state *alerts.State) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	_, err = datastore.Put(ctx, getAlertStateKey(ctx), &AlertStateEntry{Data: data})
+	return err
+}
+
+func GetOpenIssues(issues []*gcode.Issue) []*gcode.Issue {
+	open := make([]*gcode.Issue, 0, len(issues))
+	for _, issue := range issues {
+		if issue.State != "closed" {
+			open = append(open, issue)
+		}
+	}
+	return open
+}
+
+// MailNotifier sends the email digest with the mail API, to the app's admins
+// if no recipients are configured.
+type MailNotifier struct {
+	ctx   appengine.Context
+	email *alerts.Email
+}
+
+func (n *MailNotifier) Notify(events []*alerts.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	subject, body := n.email.Digest(events)
+	msg := &mail.Message{Sender: n.email.From, To: n.email.To, Subject: subject, Body: body}
+	if len(msg.To) == 0 {
+		return mail.SendToAdmins(n.ctx, msg)
+	}
+	return mail.Send(n.ctx, msg)
+}
+
+func RunAlerts(ctx appengine.Context, now time.Time) error {
+	data, err := ioutil.ReadFile(alertsConfigFile)
+	if err != nil {
+		return err
+	}
+	config, err := alerts.ParseConfig(data)
+	if err != nil {
+		return err
+	}
+	// Outbound SMTP isn't available on App Engine, so email goes through
+	// the mail API instead
+	emailConfig := config.Email
+	config.Email = nil
+	notifiers := config.Notifiers(urlfetch.Client(ctx))
+	if emailConfig != nil {
+		notifiers = append(notifiers, &MailNotifier{ctx: ctx, email: emailConfig.Email()})
+	}
+
+	issues, err := GetAllIssuesWithLabel(ctx, syncLabel)
+	if err != nil {
+		return err
+	}
+	metrics := analytics.HealthMetrics(GetOpenIssues(issues), currentMilestone)
+
+	state, err := GetAlertState(ctx)
+	if err != nil {
+		return err
+	}
+	events := alerts.Evaluate(config.Rules, state, now, metrics)
+	err = SetAlertState(ctx, state)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		ctx.Infof("Alert: %v", event.String())
+	}
+	return alerts.NotifyAll(notifiers, events)
+}
@@ -0,0 +1,116 @@
+package gae
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"appengine"
+	"appengine/datastore"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+
+	"github.com/tbuckley/go-issuetracker/bot"
+	"github.com/tbuckley/go-issuetracker/gcode"
+	"github.com/tbuckley/go-issuetracker/query"
+)
+
+const (
+	botRulesFile   = "bot.json"
+	botMaxActions  = 50
+	botInterval    = 2 * time.Second
+	issuesScopeURL = "https://code.google.com/feeds/issues"
+)
+
+type BotAction struct {
+	Rule    string
+	IssueID int
+	Time    time.Time
+}
+
+type AuditEntry struct {
+	Rule    string
+	IssueID int
+	Diff    []string `datastore:",noindex"`
+	Time    time.Time
+	DryRun  bool
+	Error   string `datastore:",noindex"`
+}
+
+// datastoreLedger stores bot actions and audit entries as children of the
+// issue they apply to.
+type datastoreLedger struct {
+	ctx appengine.Context
+}
+
+func getBotActionKey(ctx appengine.Context, rule string, issueID int) *datastore.Key {
+	issueKey := datastore.NewKey(ctx, "Issue", strconv.Itoa(issueID), 0, nil)
+	return datastore.NewKey(ctx, "BotAction", rule, 0, issueKey)
+}
+
+func (l *datastoreLedger) Applied(rule string, issueID int) (bool, error) {
+	action := new(BotAction)
+	err := datastore.Get(l.ctx, getBotActionKey(l.ctx, rule, issueID), action)
+	if err == datastore.ErrNoSuchEntity {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (l *datastoreLedger) Record(entry *bot.AuditEntry) error {
+	issueKey := datastore.NewKey(l.ctx, "Issue", strconv.Itoa(entry.IssueID), 0, nil)
+	auditKey := datastore.NewIncompleteKey(l.ctx, "AuditEntry", issueKey)
+	_, err := datastore.Put(l.ctx, auditKey, &AuditEntry{
+		Rule:    entry.Rule,
+		IssueID: entry.IssueID,
+		Diff:    entry.Diff,
+		Time:    entry.Time,
+		DryRun:  entry.DryRun,
+		Error:   entry.Error,
+	})
+	if err != nil || entry.Error != "" || entry.DryRun {
+		return err
+	}
+	_, err = datastore.Put(l.ctx, getBotActionKey(l.ctx, entry.Rule, entry.IssueID), &BotAction{
+		Rule:    entry.Rule,
+		IssueID: entry.IssueID,
+		Time:    entry.Time,
+	})
+	return err
+}
+
+func getAuthorizedClient(ctx appengine.Context) *http.Client {
+	return oauth2.NewClient(ctx, google.AppEngineTokenSource(ctx, issuesScopeURL))
+}
+
+func RunBot(ctx appengine.Context, workgroup *query.WorkGroup, issues []*gcode.Issue, now time.Time) error {
+	data, err := ioutil.ReadFile(botRulesFile)
+	if err != nil {
+		return err
+	}
+	rules, err := bot.ParseRules(data)
+	if err != nil {
+		return err
+	}
+
+	client := getAuthorizedClient(ctx)
+	b := &bot.Bot{
+		Rules:  rules,
+		Ledger: &datastoreLedger{ctx},
+		NewUpdate: func(issue *gcode.Issue) *query.Update {
+			return workgroup.NewUpdate(syncProject, issue.ID).Client(client)
+		},
+		MaxActions: botMaxActions,
+		Interval:   botInterval,
+	}
+	entries, err := b.Run(issues, now)
+	for _, entry := range entries {
+		if entry.Error != "" {
+			ctx.Errorf("Bot rule %v failed on issue %v: %v", entry.Rule, entry.IssueID, entry.Error)
+		} else {
+			ctx.Infof("Bot rule %v applied to issue %v", entry.Rule, entry.IssueID)
+		}
+	}
+	return err
+}
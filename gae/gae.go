@@ -10,6 +10,20 @@ func init() {
 	r := mux.NewRouter()
 
 	r.HandleFunc("/api/issues/{label}", HandleGetIssues).Methods("GET")
+	r.HandleFunc("/api/trends/{label}", HandleGetTrend).Methods("GET")
+	r.HandleFunc("/api/graph/{label}", HandleGetGraph).Methods("GET")
+	r.HandleFunc("/api/changes", HandleGetChanges).Methods("GET")
+	r.HandleFunc("/api/search", HandleSearch).Methods("GET")
+	r.Handle("/metrics", HandleMetrics).Methods("GET")
+
+	r.HandleFunc("/api/queries", HandleListSavedQueries).Methods("GET")
+	r.HandleFunc("/api/queries", HandleCreateSavedQuery).Methods("POST")
+	r.HandleFunc("/api/queries/{name}", HandleGetSavedQuery).Methods("GET")
+	r.HandleFunc("/api/queries/{name}", HandleUpdateSavedQuery).Methods("PUT")
+	r.HandleFunc("/api/queries/{name}", HandleDeleteSavedQuery).Methods("DELETE")
+	r.HandleFunc("/api/watchlist", HandleGetWatchlist).Methods("GET")
+	r.HandleFunc("/api/watchlist/{id}", HandleWatchIssue).Methods("PUT")
+	r.HandleFunc("/api/watchlist/{id}", HandleUnwatchIssue).Methods("DELETE")
 
 	r.HandleFunc("/tasks/issues/reset", HandleResetIssues).Methods("GET")
 	r.HandleFunc("/tasks/issues/update", HandleUpdateIssues).Methods("GET")
@@ -0,0 +1,41 @@
+package gae
+
+import (
+	"net/http"
+
+	"appengine"
+	"appengine/urlfetch"
+	"github.com/gorilla/mux"
+
+	"github.com/tbuckley/go-issuetracker/graph"
+	"github.com/tbuckley/go-issuetracker/query"
+)
+
+// HandleGetGraph returns the blocking graph of the open stored issues with a label
+// as JSON, fetching referenced issues that aren't stored from the tracker.
+func HandleGetGraph(w http.ResponseWriter, r *http.Request) {
+	ctx := appengine.NewContext(r)
+
+	issues, err := GetAllIssuesWithLabel(ctx, mux.Vars(r)["label"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	g := graph.New(GetOpenIssues(issues))
+
+	// Stored issues are cheaper than the tracker
+	stored, err := GetIssuesByID(ctx, g.Missing())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	g.Add(stored)
+
+	fetch := workgroup.NewIssues(syncProject).Client(urlfetch.Client(ctx)).Priority(query.Interactive)
+	err = g.Resolve(graph.QueryFetcher(fetch), 3)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, g)
+}
@@ -12,10 +12,30 @@ import (
 	"appengine/urlfetch"
 	"github.com/gorilla/mux"
 
+	"github.com/tbuckley/go-issuetracker/analytics"
+	"github.com/tbuckley/go-issuetracker/common"
 	"github.com/tbuckley/go-issuetracker/gcode"
 	"github.com/tbuckley/go-issuetracker/query"
 )
 
+const (
+	syncProject = "chromium"
+	syncLabel   = "cr-ui-settings"
+)
+
+// limiter and workgroup are shared by every request served by the instance,
+// so that interactive fetches are served ahead of background syncs.
+var (
+	limiter   = query.NewRateLimiter(5, 10)
+	workgroup = newWorkGroup()
+)
+
+func newWorkGroup() *query.WorkGroup {
+	g := query.NewWorkGroup(4)
+	g.SetRateLimiter(limiter)
+	return g
+}
+
 type Response struct {
 	Issues map[string]*gcode.Issue `json:"issues"`
 }
@@ -46,6 +66,41 @@ func HandleGetIssues(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func HandleGetTrend(w http.ResponseWriter, r *http.Request) {
+	ctx := appengine.NewContext(r)
+
+	// Get label and range
+	vars := mux.Vars(r)
+	label := vars["label"]
+	weeks, err := strconv.Atoi(r.URL.Query().Get("weeks"))
+	if err != nil || weeks <= 0 {
+		weeks = 12
+	}
+	bucketer := common.WeekBuckets(time.UTC)
+	end := bucketer.Next(bucketer.Start(time.Now()))
+	start := end.AddDate(0, 0, -7*weeks)
+
+	// Only open issues are stored, so the trend is fetched from the tracker
+	// to count the issues closed in range
+	q := workgroup.NewQuery(syncProject).Client(urlfetch.Client(ctx)).Label(label).Priority(query.Interactive)
+	trend, err := analytics.FetchCreatedVsResolved(q, bucketer, start, end)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Return trend
+	data, err := json.Marshal(trend)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, err = w.Write(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 func HandleResetIssues(w http.ResponseWriter, r *http.Request) {
 	ctx := appengine.NewContext(r)
 
@@ -65,11 +120,10 @@ func HandleResetIssues(w http.ResponseWriter, r *http.Request) {
 
 	// Get new issues
 	utcNow := time.Now().UTC()
-	workgroup := query.NewWorkGroup(1)
 	client := urlfetch.Client(ctx)
-	q := workgroup.NewQuery("chromium").Client(client)
-	q = q.Label("cr-ui-settings").Open()
-	issuesChan := query.BatchIssues(q.FetchAllIssues(), 25)
+	q := workgroup.NewQuery(syncProject).Client(client)
+	q = q.Label(syncLabel).Open().Priority(query.Background)
+	issuesChan := query.BatchIssues(q.FetchAllIssuesWithReplies(), 25)
 	for optionalIssues := range issuesChan {
 		log.Printf("Handling issues!")
 		if optionalIssues.Error != nil {
@@ -82,6 +136,11 @@ func HandleResetIssues(w http.ResponseWriter, r *http.Request) {
 				ctx.Errorf("Error inserting batch of initial issues: %v", err.Error())
 				return
 			}
+			err = SaveReplies(ctx, optionalIssues.Issues)
+			if err != nil {
+				ctx.Errorf("Error inserting replies of initial issues: %v", err.Error())
+				return
+			}
 			ctx.Infof("Successfully added batch of %v initial issues", len(optionalIssues.Issues))
 		}
 	}
@@ -97,7 +156,92 @@ func HandleResetIssues(w http.ResponseWriter, r *http.Request) {
 }
 
 func HandleUpdateIssues(w http.ResponseWriter, r *http.Request) {
+	ctx := appengine.NewContext(r)
+
+	utcNow := time.Now().UTC()
+	issues, err := syncIssues(ctx, workgroup, utcNow)
+	if recordErr := RecordSync(ctx, utcNow, len(issues), err); recordErr != nil {
+		ctx.Errorf("Error recording sync status: %v", recordErr.Error())
+	}
+	if err != nil {
+		return
+	}
+
+	// Let the bot act on the changed issues
+	err = RunBot(ctx, workgroup, issues, utcNow)
+	if err != nil {
+		ctx.Errorf("Error running bot: %v", err.Error())
+	}
+
+	// Keep a snapshot of the day's issues for change reports
+	allIssues, allErr := GetAllIssues(ctx)
+	err = allErr
+	if err == nil {
+		err = SaveSnapshot(ctx, allIssues, utcNow)
+	}
+	if err != nil {
+		ctx.Errorf("Error saving snapshot: %v", err.Error())
+	}
+
+	// Refresh saved queries and watchlists
+	err = RecomputeSavedQueries(ctx, workgroup, utcNow)
+	if err != nil {
+		ctx.Errorf("Error recomputing saved queries: %v", err.Error())
+	}
+
+	// Check alert thresholds against the updated issues
+	err = RunAlerts(ctx, utcNow)
+	if err != nil {
+		ctx.Errorf("Error running alerts: %v", err.Error())
+	}
+
+	// Refresh the gauges served on /metrics
+	if allErr == nil {
+		err = SaveMetrics(ctx, allIssues, utcNow)
+		if err != nil {
+			ctx.Errorf("Error saving metrics: %v", err.Error())
+		}
+	}
+}
+
+// syncIssues stores the issues changed since the last update.
+func syncIssues(ctx appengine.Context, workgroup *query.WorkGroup, utcNow time.Time) ([]*gcode.Issue, error) {
+	lastUpdate, err := GetLastUpdateTime(ctx)
+	if err != nil {
+		ctx.Errorf("Error getting last update time: %v", err.Error())
+		return nil, err
+	}
 
+	// Get issues changed since the last update
+	client := urlfetch.Client(ctx)
+	q := workgroup.NewQuery(syncProject).Client(client)
+	q = q.Label(syncLabel).All().UpdatedAfter(lastUpdate).Priority(query.Background)
+	issues, err := query.CollectIssues(q.FetchAllIssuesWithReplies())
+	if err != nil {
+		ctx.Errorf("Error while fetching updated issues: %v", err.Error())
+		return nil, err
+	}
+
+	// Store them
+	if len(issues) > 0 {
+		err = UpdateIssues(ctx, issues)
+		if err != nil {
+			ctx.Errorf("Error storing updated issues: %v", err.Error())
+			return nil, err
+		}
+		err = SaveReplies(ctx, issues)
+		if err != nil {
+			ctx.Errorf("Error storing replies of updated issues: %v", err.Error())
+			return nil, err
+		}
+	}
+	ctx.Infof("Successfully updated %v issues", len(issues))
+	err = SetLastUpdateTime(ctx, utcNow)
+	if err != nil {
+		ctx.Errorf("Error setting last update time: %v", err.Error())
+		return nil, err
+	}
+	return issues, nil
 }
 
 func GetIssueKey(ctx appengine.Context, issue *gcode.Issue) *datastore.Key {
@@ -112,6 +256,13 @@ func GetAllIssuesWithLabel(ctx appengine.Context, label string) ([]*gcode.Issue,
 	return issues, err
 }
 
+func GetAllIssues(ctx appengine.Context) ([]*gcode.Issue, error) {
+	q := datastore.NewQuery("Issue")
+	issues := make([]*gcode.Issue, 0)
+	_, err := q.GetAll(ctx, &issues)
+	return issues, err
+}
+
 func AddIssues(ctx appengine.Context, issues []*gcode.Issue) error {
 	incompleteKeys := make([]*datastore.Key, len(issues))
 	for i, issue := range issues {
@@ -131,10 +282,15 @@ func UpdateIssues(ctx appengine.Context, issues []*gcode.Issue) error {
 }
 
 func DeleteAllIssues(ctx appengine.Context) error {
-	q := datastore.NewQuery("Issue")
-	keys, err := q.KeysOnly().GetAll(ctx, nil)
-	if err != nil {
-		return err
+	for _, kind := range []string{"Issue", "Replies"} {
+		keys, err := datastore.NewQuery(kind).KeysOnly().GetAll(ctx, nil)
+		if err != nil {
+			return err
+		}
+		err = datastore.DeleteMulti(ctx, keys)
+		if err != nil {
+			return err
+		}
 	}
-	return datastore.DeleteMulti(ctx, keys)
+	return nil
 }
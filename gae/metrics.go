@@ -0,0 +1,90 @@
+package gae
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"appengine"
+	"appengine/datastore"
+
+	"github.com/tbuckley/go-issuetracker/gcode"
+	"github.com/tbuckley/go-issuetracker/metrics"
+)
+
+type MetricsEntry struct {
+	Data     []byte `datastore:",noindex"`
+	Computed time.Time
+}
+
+func getSyncStatusKey(ctx appengine.Context) *datastore.Key {
+	return datastore.NewKey(ctx, "SyncStatus", "sync", 0, nil)
+}
+
+func getMetricsKey(ctx appengine.Context) *datastore.Key {
+	return datastore.NewKey(ctx, "MetricsEntry", "issues", 0, nil)
+}
+
+func GetSyncStatus(ctx appengine.Context) (*metrics.SyncStatus, error) {
+	status := new(metrics.SyncStatus)
+	err := datastore.Get(ctx, getSyncStatusKey(ctx), status)
+	if err == datastore.ErrNoSuchEntity {
+		return status, nil
+	}
+	return status, err
+}
+
+// RecordSync updates the sync health with the outcome of a sync that began
+// at start.
+func RecordSync(ctx appengine.Context, start time.Time, issues int, syncErr error) error {
+	status, err := GetSyncStatus(ctx)
+	if err != nil {
+		return err
+	}
+	if syncErr != nil {
+		status.Failure(start, time.Now().UTC())
+	} else {
+		status.Success(start, time.Now().UTC(), issues)
+	}
+	_, err = datastore.Put(ctx, getSyncStatusKey(ctx), status)
+	return err
+}
+
+// SaveMetrics computes the issue gauges so that scrapes don't have to load
+// every issue.
+func SaveMetrics(ctx appengine.Context, issues []*gcode.Issue, now time.Time) error {
+	data, err := json.Marshal(metrics.IssueFamilies(issues))
+	if err != nil {
+		return err
+	}
+	_, err = datastore.Put(ctx, getMetricsKey(ctx), &MetricsEntry{Data: data, Computed: now})
+	return err
+}
+
+func GetMetrics(ctx appengine.Context) ([]*metrics.Family, error) {
+	entry := new(MetricsEntry)
+	err := datastore.Get(ctx, getMetricsKey(ctx), entry)
+	if err == datastore.ErrNoSuchEntity {
+		return make([]*metrics.Family, 0), nil
+	} else if err != nil {
+		return nil, err
+	}
+	families := make([]*metrics.Family, 0)
+	err = json.Unmarshal(entry.Data, &families)
+	return families, err
+}
+
+func collectMetrics(r *http.Request) ([]*metrics.Family, error) {
+	ctx := appengine.NewContext(r)
+	families, err := GetMetrics(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status, err := GetSyncStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return append(families, status.Families()...), nil
+}
+
+var HandleMetrics = metrics.Handler(collectMetrics)
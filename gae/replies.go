@@ -0,0 +1,81 @@
+package gae
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"appengine"
+	"appengine/datastore"
+
+	"github.com/tbuckley/go-issuetracker/gcode"
+)
+
+// maxBatch keeps datastore batch calls within their limits.
+const maxBatch = 500
+
+// RepliesEntry holds the replies of an issue, which are kept apart from the
+// issue since the datastore can't nest them.
+type RepliesEntry struct {
+	Data []byte `datastore:",noindex"`
+}
+
+func getRepliesKey(ctx appengine.Context, id int) *datastore.Key {
+	return datastore.NewKey(ctx, "Replies", strconv.Itoa(id), 0, nil)
+}
+
+func SaveReplies(ctx appengine.Context, issues []*gcode.Issue) error {
+	for start := 0; start < len(issues); start += maxBatch {
+		batch := issues[start:minInt(start+maxBatch, len(issues))]
+		keys := make([]*datastore.Key, len(batch))
+		entries := make([]*RepliesEntry, len(batch))
+		for i, issue := range batch {
+			data, err := json.Marshal(issue.Replies)
+			if err != nil {
+				return err
+			}
+			keys[i] = getRepliesKey(ctx, issue.ID)
+			entries[i] = &RepliesEntry{Data: data}
+		}
+		if _, err := datastore.PutMulti(ctx, keys, entries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadReplies fills in the stored replies of issues. Issues whose replies
+// were never stored are left without.
+func LoadReplies(ctx appengine.Context, issues []*gcode.Issue) error {
+	for start := 0; start < len(issues); start += maxBatch {
+		batch := issues[start:minInt(start+maxBatch, len(issues))]
+		keys := make([]*datastore.Key, len(batch))
+		entries := make([]*RepliesEntry, len(batch))
+		for i, issue := range batch {
+			keys[i] = getRepliesKey(ctx, issue.ID)
+			entries[i] = new(RepliesEntry)
+		}
+		err := datastore.GetMulti(ctx, keys, entries)
+		multiErr, _ := err.(appengine.MultiError)
+		if err != nil && multiErr == nil {
+			return err
+		}
+		for i, issue := range batch {
+			if multiErr != nil && multiErr[i] == datastore.ErrNoSuchEntity {
+				continue
+			} else if multiErr != nil && multiErr[i] != nil {
+				return multiErr[i]
+			}
+			if err := json.Unmarshal(entries[i].Data, &issue.Replies); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,491 @@
+package gae
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"appengine"
+	"appengine/datastore"
+	"appengine/urlfetch"
+	"appengine/user"
+	"github.com/gorilla/mux"
+
+	"github.com/tbuckley/go-issuetracker/common"
+	"github.com/tbuckley/go-issuetracker/filter"
+	"github.com/tbuckley/go-issuetracker/gcode"
+	"github.com/tbuckley/go-issuetracker/query"
+)
+
+type SavedQuery struct {
+	Name     string    `json:"name"`
+	Filter   string    `json:"filter" datastore:",noindex"`
+	Columns  []string  `json:"columns" datastore:",noindex"`
+	Sort     string    `json:"sort" datastore:",noindex"`
+	Results  []int     `json:"results" datastore:",noindex"`
+	Computed time.Time `json:"computed"`
+}
+
+type Watchlist struct {
+	IssueIDs   []int     `json:"issues" datastore:",noindex"`
+	Changed    []int     `json:"changed" datastore:",noindex"`
+	LastViewed time.Time `json:"lastViewed"`
+}
+
+type Row struct {
+	ID      int                 `json:"id"`
+	Changed bool                `json:"changed,omitempty"`
+	Values  map[string][]string `json:"values"`
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func getUserKey(ctx appengine.Context) (*datastore.Key, bool) {
+	u := user.Current(ctx)
+	if u == nil {
+		return nil, false
+	}
+	return datastore.NewKey(ctx, "User", u.Email, 0, nil), true
+}
+
+func getSavedQueryKey(ctx appengine.Context, userKey *datastore.Key, name string) *datastore.Key {
+	return datastore.NewKey(ctx, "SavedQuery", name, 0, userKey)
+}
+
+func getWatchlistKey(ctx appengine.Context, userKey *datastore.Key) *datastore.Key {
+	return datastore.NewKey(ctx, "Watchlist", "watchlist", 0, userKey)
+}
+
+// ComputeResults runs the saved query against issues, storing the matching
+// IDs in the requested order.
+func (s *SavedQuery) ComputeResults(issues []*gcode.Issue, now time.Time) error {
+	f, err := filter.Parse(s.Filter)
+	if err != nil {
+		return err
+	}
+	matched := f.Apply(issues)
+	if s.Sort != "" {
+		common.SortIssues(matched, s.Sort)
+	}
+	s.Results = make([]int, len(matched))
+	for i, issue := range matched {
+		s.Results[i] = issue.ID
+	}
+	s.Computed = now
+	return nil
+}
+
+func (s *SavedQuery) Validate() error {
+	if s.Name == "" {
+		return &ValidationError{"name is required"}
+	}
+	if _, err := filter.Parse(s.Filter); err != nil {
+		return &ValidationError{err.Error()}
+	}
+	for _, column := range s.Columns {
+		if _, ok := common.LookupField(column); !ok {
+			return &ValidationError{"unknown column: " + column}
+		}
+	}
+	if s.Sort != "" {
+		if _, ok := common.LookupField(trimSortPrefix(s.Sort)); !ok {
+			return &ValidationError{"unknown sort column: " + s.Sort}
+		}
+	}
+	return nil
+}
+
+func trimSortPrefix(sort string) string {
+	if len(sort) > 0 && sort[0] == '-' {
+		return sort[1:]
+	}
+	return sort
+}
+
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func GetRows(issues []*gcode.Issue, columns []string, changed []int) []*Row {
+	isChanged := make(map[int]bool, len(changed))
+	for _, id := range changed {
+		isChanged[id] = true
+	}
+	rows := make([]*Row, len(issues))
+	for i, issue := range issues {
+		row := &Row{ID: issue.ID, Changed: isChanged[issue.ID], Values: make(map[string][]string)}
+		for _, column := range columns {
+			if field, ok := common.LookupField(column); ok {
+				row.Values[column] = field.Values(issue)
+			}
+		}
+		rows[i] = row
+	}
+	return rows
+}
+
+func GetIssuesByID(ctx appengine.Context, ids []int) ([]*gcode.Issue, error) {
+	keys := make([]*datastore.Key, len(ids))
+	for i, id := range ids {
+		keys[i] = datastore.NewKey(ctx, "Issue", strconv.Itoa(id), 0, nil)
+	}
+	issues := make([]*gcode.Issue, len(ids))
+	for i := range issues {
+		issues[i] = new(gcode.Issue)
+	}
+	err := datastore.GetMulti(ctx, keys, issues)
+	if multiErr, ok := err.(appengine.MultiError); ok {
+		found := make([]*gcode.Issue, 0, len(issues))
+		for i, err := range multiErr {
+			if err == nil {
+				found = append(found, issues[i])
+			} else if err != datastore.ErrNoSuchEntity {
+				return nil, err
+			}
+		}
+		return found, nil
+	}
+	return issues, err
+}
+
+// SAVED QUERIES
+
+func HandleListSavedQueries(w http.ResponseWriter, r *http.Request) {
+	ctx := appengine.NewContext(r)
+	userKey, ok := getUserKey(ctx)
+	if !ok {
+		http.Error(w, "login required", http.StatusUnauthorized)
+		return
+	}
+
+	queries := make([]*SavedQuery, 0)
+	_, err := datastore.NewQuery("SavedQuery").Ancestor(userKey).GetAll(ctx, &queries)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, queries)
+}
+
+func putSavedQuery(w http.ResponseWriter, r *http.Request, name string) {
+	ctx := appengine.NewContext(r)
+	userKey, ok := getUserKey(ctx)
+	if !ok {
+		http.Error(w, "login required", http.StatusUnauthorized)
+		return
+	}
+
+	saved := new(SavedQuery)
+	err := json.NewDecoder(r.Body).Decode(saved)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if name != "" {
+		saved.Name = name
+	}
+	err = saved.Validate()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	issues, err := GetAllIssues(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = saved.ComputeResults(issues, time.Now().UTC())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, err = datastore.Put(ctx, getSavedQueryKey(ctx, userKey, saved.Name), saved)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, saved)
+}
+
+func HandleCreateSavedQuery(w http.ResponseWriter, r *http.Request) {
+	putSavedQuery(w, r, "")
+}
+
+func HandleUpdateSavedQuery(w http.ResponseWriter, r *http.Request) {
+	putSavedQuery(w, r, mux.Vars(r)["name"])
+}
+
+func HandleGetSavedQuery(w http.ResponseWriter, r *http.Request) {
+	ctx := appengine.NewContext(r)
+	userKey, ok := getUserKey(ctx)
+	if !ok {
+		http.Error(w, "login required", http.StatusUnauthorized)
+		return
+	}
+
+	saved := new(SavedQuery)
+	err := datastore.Get(ctx, getSavedQueryKey(ctx, userKey, mux.Vars(r)["name"]), saved)
+	if err == datastore.ErrNoSuchEntity {
+		http.Error(w, "no such saved query", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	issues, err := GetIssuesByID(ctx, saved.Results)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"query": saved,
+		"rows":  GetRows(issues, saved.Columns, nil),
+	})
+}
+
+func HandleDeleteSavedQuery(w http.ResponseWriter, r *http.Request) {
+	ctx := appengine.NewContext(r)
+	userKey, ok := getUserKey(ctx)
+	if !ok {
+		http.Error(w, "login required", http.StatusUnauthorized)
+		return
+	}
+
+	err := datastore.Delete(ctx, getSavedQueryKey(ctx, userKey, mux.Vars(r)["name"]))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// WATCHLISTS
+
+func getWatchlist(ctx appengine.Context, userKey *datastore.Key) (*Watchlist, error) {
+	watchlist := new(Watchlist)
+	err := datastore.Get(ctx, getWatchlistKey(ctx, userKey), watchlist)
+	if err == datastore.ErrNoSuchEntity {
+		return &Watchlist{LastViewed: time.Now().UTC()}, nil
+	}
+	return watchlist, err
+}
+
+// HandleGetWatchlist returns the watched issues, flagging those that changed
+// since the last time the user looked.
+func HandleGetWatchlist(w http.ResponseWriter, r *http.Request) {
+	ctx := appengine.NewContext(r)
+	userKey, ok := getUserKey(ctx)
+	if !ok {
+		http.Error(w, "login required", http.StatusUnauthorized)
+		return
+	}
+
+	watchlist, err := getWatchlist(ctx, userKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	fetch := workgroup.NewIssues(syncProject).Client(urlfetch.Client(ctx)).Priority(query.Interactive)
+	issues, err := getWatchedIssues(ctx, fetch, watchlist.IssueIDs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	columns := r.URL.Query()["column"]
+	if len(columns) == 0 {
+		columns = []string{"title", "status", "owner", "priority", "updated"}
+	}
+	rows := GetRows(issues, columns, watchlist.Changed)
+
+	watchlist.Changed = nil
+	watchlist.LastViewed = time.Now().UTC()
+	_, err = datastore.Put(ctx, getWatchlistKey(ctx, userKey), watchlist)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]interface{}{"rows": rows})
+}
+
+// getWatchedIssues returns the watched issues in order, fetching those that
+// aren't synced from the tracker. Issues that no longer exist are skipped.
+func getWatchedIssues(ctx appengine.Context, fetch *query.Issues, ids []int) ([]*gcode.Issue, error) {
+	stored, err := GetIssuesByID(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]*gcode.Issue, len(ids))
+	for _, issue := range stored {
+		byID[issue.ID] = issue
+	}
+
+	missing := make([]int, 0)
+	for _, id := range ids {
+		if _, ok := byID[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		fetched, err := fetch.GetAll(missing)
+		var notFound *query.NotFoundError
+		if errors.As(err, &notFound) {
+			ctx.Infof("Skipping watched issues: %v", notFound.Error())
+		} else if err != nil {
+			return nil, err
+		}
+		for _, issue := range fetched {
+			byID[issue.ID] = issue
+		}
+	}
+
+	issues := make([]*gcode.Issue, 0, len(ids))
+	for _, id := range ids {
+		if issue, ok := byID[id]; ok {
+			issues = append(issues, issue)
+		}
+	}
+	return issues, nil
+}
+
+func updateWatchlist(w http.ResponseWriter, r *http.Request, update func(watchlist *Watchlist, id int)) {
+	ctx := appengine.NewContext(r)
+	userKey, ok := getUserKey(ctx)
+	if !ok {
+		http.Error(w, "login required", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid issue id", http.StatusBadRequest)
+		return
+	}
+
+	watchlist, err := getWatchlist(ctx, userKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	update(watchlist, id)
+	_, err = datastore.Put(ctx, getWatchlistKey(ctx, userKey), watchlist)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, watchlist)
+}
+
+func removeID(ids []int, id int) []int {
+	kept := make([]int, 0, len(ids))
+	for _, existing := range ids {
+		if existing != id {
+			kept = append(kept, existing)
+		}
+	}
+	return kept
+}
+
+func HandleWatchIssue(w http.ResponseWriter, r *http.Request) {
+	updateWatchlist(w, r, func(watchlist *Watchlist, id int) {
+		watchlist.IssueIDs = append(removeID(watchlist.IssueIDs, id), id)
+	})
+}
+
+func HandleUnwatchIssue(w http.ResponseWriter, r *http.Request) {
+	updateWatchlist(w, r, func(watchlist *Watchlist, id int) {
+		watchlist.IssueIDs = removeID(watchlist.IssueIDs, id)
+		watchlist.Changed = removeID(watchlist.Changed, id)
+	})
+}
+
+// RecomputeSavedQueries refreshes every user's saved query results and flags
+// watched issues updated since the user last viewed their watchlist. Watched
+// issues outside the synced label are fetched with workgroup.
+func RecomputeSavedQueries(ctx appengine.Context, workgroup *query.WorkGroup, now time.Time) error {
+	issues, err := GetAllIssues(ctx)
+	if err != nil {
+		return err
+	}
+	byID := make(map[int]*gcode.Issue, len(issues))
+	for _, issue := range issues {
+		byID[issue.ID] = issue
+	}
+
+	queries := make([]*SavedQuery, 0)
+	queryKeys, err := datastore.NewQuery("SavedQuery").GetAll(ctx, &queries)
+	if err != nil {
+		return err
+	}
+	for _, saved := range queries {
+		if err := saved.ComputeResults(issues, now); err != nil {
+			ctx.Errorf("Error computing saved query %v: %v", saved.Name, err.Error())
+		}
+	}
+	if len(queries) > 0 {
+		if _, err := datastore.PutMulti(ctx, queryKeys, queries); err != nil {
+			return err
+		}
+	}
+
+	watchlists := make([]*Watchlist, 0)
+	watchlistKeys, err := datastore.NewQuery("Watchlist").GetAll(ctx, &watchlists)
+	if err != nil {
+		return err
+	}
+	unsynced := make([]int, 0)
+	for _, watchlist := range watchlists {
+		for _, id := range watchlist.IssueIDs {
+			if _, ok := byID[id]; !ok {
+				unsynced = append(unsynced, id)
+			}
+		}
+	}
+	if len(unsynced) > 0 {
+		fetch := workgroup.NewIssues(syncProject).Client(urlfetch.Client(ctx)).Priority(query.Background)
+		fetched, err := fetch.GetAll(unsynced)
+		var notFound *query.NotFoundError
+		if !errors.As(err, &notFound) && err != nil {
+			return err
+		}
+		for _, issue := range fetched {
+			byID[issue.ID] = issue
+		}
+	}
+
+	for _, watchlist := range watchlists {
+		for _, id := range watchlist.IssueIDs {
+			issue, ok := byID[id]
+			if !ok {
+				continue
+			}
+			updated, ok := common.GetIssueUpdated(issue)
+			if ok && updated.After(watchlist.LastViewed) {
+				watchlist.Changed = append(removeID(watchlist.Changed, id), id)
+			}
+		}
+	}
+	if len(watchlists) > 0 {
+		if _, err := datastore.PutMulti(ctx, watchlistKeys, watchlists); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package gae
+
+import (
+	"net/http"
+	"strconv"
+
+	"appengine"
+
+	"github.com/tbuckley/go-issuetracker/search"
+)
+
+type SearchResponse struct {
+	Query   string           `json:"query"`
+	Indexed int              `json:"indexed"`
+	Results []*search.Result `json:"results"`
+}
+
+// HandleSearch ranks the stored issues and their replies against ?q=, which
+// may contain quoted phrases. The index is rebuilt on every request.
+func HandleSearch(w http.ResponseWriter, r *http.Request) {
+	ctx := appengine.NewContext(r)
+	params := r.URL.Query()
+
+	text := params.Get("q")
+	if text == "" {
+		http.Error(w, "missing q", http.StatusBadRequest)
+		return
+	}
+	limit := 50
+	if params.Get("limit") != "" {
+		parsed, err := strconv.Atoi(params.Get("limit"))
+		if err != nil || parsed <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	issues, err := GetAllIssues(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = LoadReplies(ctx, issues)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	index := search.Build(issues)
+	results := index.Search(text, limit)
+	if results == nil {
+		results = make([]*search.Result, 0)
+	}
+	writeJSON(w, &SearchResponse{Query: text, Indexed: index.Len(), Results: results})
+}
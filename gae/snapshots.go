@@ -0,0 +1,121 @@
+package gae
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"appengine"
+	"appengine/datastore"
+
+	"github.com/tbuckley/go-issuetracker/changes"
+	"github.com/tbuckley/go-issuetracker/gcode"
+)
+
+// Snapshot stores the issues as of a day, as gzipped JSON. The sync overwrites
+// the current day's snapshot, so each day keeps its latest state.
+type Snapshot struct {
+	Taken time.Time
+	Data  []byte `datastore:",noindex"`
+}
+
+func getSnapshotKey(ctx appengine.Context, day time.Time) *datastore.Key {
+	return datastore.NewKey(ctx, "Snapshot", day.Format("2006-01-02"), 0, nil)
+}
+
+func SaveSnapshot(ctx appengine.Context, issues []*gcode.Issue, now time.Time) error {
+	buf := new(bytes.Buffer)
+	writer := gzip.NewWriter(buf)
+	err := json.NewEncoder(writer).Encode(issues)
+	if err != nil {
+		return err
+	}
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+	_, err = datastore.Put(ctx, getSnapshotKey(ctx, now), &Snapshot{Taken: now, Data: buf.Bytes()})
+	return err
+}
+
+func GetSnapshot(ctx appengine.Context, day time.Time) ([]*gcode.Issue, time.Time, error) {
+	snapshot := new(Snapshot)
+	err := datastore.Get(ctx, getSnapshotKey(ctx, day), snapshot)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(snapshot.Data))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	issues := make([]*gcode.Issue, 0)
+	err = json.Unmarshal(data, &issues)
+	return issues, snapshot.Taken, err
+}
+
+// HandleGetChanges diffs the snapshots of two days (?from=2015-02-11&to=...),
+// defaulting to the last week up to the current issues. The format parameter
+// selects json (default), markdown or text.
+func HandleGetChanges(w http.ResponseWriter, r *http.Request) {
+	ctx := appengine.NewContext(r)
+	params := r.URL.Query()
+
+	from := time.Now().UTC().AddDate(0, 0, -7)
+	if params.Get("from") != "" {
+		parsed, err := time.Parse("2006-01-02", params.Get("from"))
+		if err != nil {
+			http.Error(w, "invalid from date", http.StatusBadRequest)
+			return
+		}
+		from = parsed
+	}
+	before, fromTaken, err := GetSnapshot(ctx, from)
+	if err == datastore.ErrNoSuchEntity {
+		http.Error(w, "no snapshot for "+from.Format("2006-01-02"), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var after []*gcode.Issue
+	toTaken := time.Now().UTC()
+	if params.Get("to") != "" {
+		var to time.Time
+		to, err = time.Parse("2006-01-02", params.Get("to"))
+		if err != nil {
+			http.Error(w, "invalid to date", http.StatusBadRequest)
+			return
+		}
+		after, toTaken, err = GetSnapshot(ctx, to)
+		if err == datastore.ErrNoSuchEntity {
+			http.Error(w, "no snapshot for "+to.Format("2006-01-02"), http.StatusNotFound)
+			return
+		}
+	} else {
+		after, err = GetAllIssues(ctx)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	changelog := changes.Diff(before, after, fromTaken, toTaken)
+	switch params.Get("format") {
+	case "markdown":
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		changelog.WriteMarkdown(w)
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		changelog.WriteText(w)
+	default:
+		writeJSON(w, changelog)
+	}
+}
@@ -35,6 +35,7 @@ type Reply struct {
 	CCChanges    []string `xml:"updates>ccUpdate"`
 	LabelChanges []string `xml:"updates>label"`
 	StatusChange string   `xml:"updates>status"`
+	OwnerChange  string   `xml:"updates>ownerUpdate"`
 }
 
 type RepliesFeed struct {
@@ -51,9 +52,10 @@ type Issue struct {
 	Stars     int      `xml:"stars"`
 	State     string   `xml:"state"`
 	Status    string   `xml:"status"`
+	Closed    string   `xml:"closedDate"`
 	BlockedOn []string `xml:"http://schemas.google.com/projecthosting/issues/2009 blockedOn>id"`
 	Blocking  []string `xml:"http://schemas.google.com/projecthosting/issues/2009 blocking>id"`
-	Replies   []*Reply
+	Replies   []*Reply `datastore:"-"`
 }
 
 func (e *Issue) RepliesURL() (string, bool) {
@@ -0,0 +1,26 @@
+package gcode
+
+import (
+	"encoding/xml"
+)
+
+const (
+	AtomNamespace   = "http://www.w3.org/2005/Atom"
+	IssuesNamespace = "http://schemas.google.com/projecthosting/issues/2009"
+)
+
+type Updates struct {
+	Summary      string   `xml:"summary,omitempty"`
+	Status       string   `xml:"status,omitempty"`
+	OwnerUpdate  string   `xml:"ownerUpdate,omitempty"`
+	LabelChanges []string `xml:"label"`
+	CCChanges    []string `xml:"ccUpdate"`
+}
+
+// UpdateEntry is the Atom entry posted to an issue's replies feed to comment
+// on it or change its fields. Removals are written as "-Value".
+type UpdateEntry struct {
+	XMLName xml.Name `xml:"http://www.w3.org/2005/Atom entry"`
+	Content string   `xml:"content,omitempty"`
+	Updates *Updates `xml:"http://schemas.google.com/projecthosting/issues/2009 updates,omitempty"`
+}
@@ -0,0 +1,56 @@
+package gcode
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUpdateEntryNamespaces(t *testing.T) {
+	entry := &UpdateEntry{
+		Content: "Fixed",
+		Updates: &Updates{Status: "Fixed", LabelChanges: []string{"Pri-1", "-Pri-2"}},
+	}
+	data, err := xml.Marshal(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := string(data)
+	for _, want := range []string{
+		`<entry xmlns="` + AtomNamespace + `">`,
+		`<updates xmlns="` + IssuesNamespace + `">`,
+		`<content>Fixed</content>`,
+		`<label>-Pri-2</label>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("%v is missing %v", body, want)
+		}
+	}
+}
+
+func TestUpdateEntryRoundTrip(t *testing.T) {
+	entry := &UpdateEntry{
+		Updates: &Updates{
+			Summary:      "New title",
+			OwnerUpdate:  "someone@chromium.org",
+			LabelChanges: []string{"M-42"},
+			CCChanges:    []string{"-other@chromium.org"},
+		},
+	}
+	data, err := xml.Marshal(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "<content>") {
+		t.Errorf("%s has an empty comment", data)
+	}
+
+	parsed := new(UpdateEntry)
+	if err := xml.Unmarshal(data, parsed); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed.Updates, entry.Updates) {
+		t.Errorf("got updates %+v, want %+v", parsed.Updates, entry.Updates)
+	}
+}
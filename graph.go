@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/tbuckley/go-issuetracker/graph"
+	"github.com/tbuckley/go-issuetracker/query"
+)
+
+func runGraph(wg *query.WorkGroup, client *http.Client, args []string) {
+	flags := flag.NewFlagSet("graph", flag.ExitOnError)
+	project := flags.String("project", "chromium", "Project of the issues")
+	q := flags.String("query", "Cr:UI", "Search query selecting the open issues to start from")
+	format := flags.String("format", "dot", "Output format: dot or json")
+	rounds := flags.Int("rounds", 5, "Maximum rounds of fetching referenced issues")
+	flags.Parse(args)
+
+	if *format != "dot" && *format != "json" {
+		fmt.Printf("Unknown format: %v\n", *format)
+		return
+	}
+
+	issues, err := query.CollectIssues(wg.NewQuery(*project).Client(client).Query(*q).FetchAllIssues())
+	if err != nil {
+		fmt.Printf("Error: %v\n", err.Error())
+		return
+	}
+	g := graph.New(issues)
+	err = g.Resolve(graph.QueryFetcher(wg.NewIssues(*project).Client(client)), *rounds)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err.Error())
+		return
+	}
+
+	if *format == "json" {
+		data, err := json.MarshalIndent(g, "", "  ")
+		if err != nil {
+			fmt.Printf("Error: %v\n", err.Error())
+			return
+		}
+		fmt.Println(string(data))
+		return
+	}
+	g.WriteDOT(os.Stdout)
+}
@@ -0,0 +1,347 @@
+package graph
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tbuckley/go-issuetracker/gcode"
+	"github.com/tbuckley/go-issuetracker/query"
+)
+
+// Fetcher loads issues that are referenced by the graph but weren't part of
+// the initial set. Issues it doesn't return are left unresolved.
+type Fetcher func(ids []int) ([]*gcode.Issue, error)
+
+// QueryFetcher fetches missing issues from the tracker. Issues that don't
+// exist, e.g. because they are restricted, are left unresolved.
+func QueryFetcher(issues *query.Issues) Fetcher {
+	return func(ids []int) ([]*gcode.Issue, error) {
+		fetched, err := issues.GetAll(ids)
+		var notFound *query.NotFoundError
+		if errors.As(err, &notFound) {
+			return fetched, nil
+		}
+		return fetched, err
+	}
+}
+
+type Node struct {
+	ID        int
+	Issue     *gcode.Issue
+	BlockedOn []int
+	Blocking  []int
+}
+
+// Open reports whether the node still needs work. Unresolved nodes are assumed
+// to be open.
+func (n *Node) Open() bool {
+	return n.Issue == nil || n.Issue.State != "closed"
+}
+
+func (n *Node) Title() string {
+	if n.Issue == nil {
+		return ""
+	}
+	return n.Issue.Title
+}
+
+type Graph struct {
+	Nodes map[int]*Node
+}
+
+// parseIDs returns the IDs of references within the project. Cross-project
+// references look like "project:123" and are skipped, since nodes are keyed by
+// ID alone.
+func parseIDs(ids []string) []int {
+	parsed := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if strings.Contains(id, ":") {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(id))
+		if err == nil {
+			parsed = append(parsed, n)
+		}
+	}
+	return parsed
+}
+
+func appendUnique(ids []int, id int) []int {
+	for _, existing := range ids {
+		if existing == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
+
+func New(issues []*gcode.Issue) *Graph {
+	g := &Graph{Nodes: make(map[int]*Node)}
+	g.Add(issues)
+	return g
+}
+
+func (g *Graph) node(id int) *Node {
+	n, ok := g.Nodes[id]
+	if !ok {
+		n = &Node{ID: id}
+		g.Nodes[id] = n
+	}
+	return n
+}
+
+func (g *Graph) addEdge(blocked, blocker int) {
+	g.node(blocked).BlockedOn = appendUnique(g.node(blocked).BlockedOn, blocker)
+	g.node(blocker).Blocking = appendUnique(g.node(blocker).Blocking, blocked)
+}
+
+func (g *Graph) Add(issues []*gcode.Issue) {
+	for _, issue := range issues {
+		g.node(issue.ID).Issue = issue
+		for _, blocker := range parseIDs(issue.BlockedOn) {
+			g.addEdge(issue.ID, blocker)
+		}
+		for _, blocked := range parseIDs(issue.Blocking) {
+			g.addEdge(blocked, issue.ID)
+		}
+	}
+}
+
+// Missing returns the IDs of referenced issues that haven't been loaded.
+func (g *Graph) Missing() []int {
+	missing := make([]int, 0)
+	for id, n := range g.Nodes {
+		if n.Issue == nil {
+			missing = append(missing, id)
+		}
+	}
+	sort.Ints(missing)
+	return missing
+}
+
+// Resolve fetches missing issues, and the issues they reference, until the
+// graph is complete or maxRounds fetches have been made.
+func (g *Graph) Resolve(fetch Fetcher, maxRounds int) error {
+	attempted := make(map[int]bool)
+	for round := 0; round < maxRounds; round++ {
+		ids := make([]int, 0)
+		for _, id := range g.Missing() {
+			if !attempted[id] {
+				attempted[id] = true
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		issues, err := fetch(ids)
+		if err != nil {
+			return err
+		}
+		g.Add(issues)
+	}
+	return nil
+}
+
+func (g *Graph) sortedIDs() []int {
+	ids := make([]int, 0, len(g.Nodes))
+	for id := range g.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// Cycles returns every set of issues that block each other, using Tarjan's
+// strongly connected components algorithm.
+func (g *Graph) Cycles() [][]int {
+	index := 0
+	indices := make(map[int]int)
+	lowlink := make(map[int]int)
+	onStack := make(map[int]bool)
+	stack := make([]int, 0)
+	cycles := make([][]int, 0)
+
+	var visit func(id int)
+	visit = func(id int) {
+		indices[id] = index
+		lowlink[id] = index
+		index++
+		stack = append(stack, id)
+		onStack[id] = true
+
+		for _, next := range g.Nodes[id].BlockedOn {
+			if _, seen := indices[next]; !seen {
+				visit(next)
+				if lowlink[next] < lowlink[id] {
+					lowlink[id] = lowlink[next]
+				}
+			} else if onStack[next] && indices[next] < lowlink[id] {
+				lowlink[id] = indices[next]
+			}
+		}
+
+		if lowlink[id] == indices[id] {
+			component := make([]int, 0)
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == id {
+					break
+				}
+			}
+			selfLoop := false
+			for _, blocker := range g.Nodes[id].BlockedOn {
+				if blocker == id {
+					selfLoop = true
+				}
+			}
+			if len(component) > 1 || selfLoop {
+				sort.Ints(component)
+				cycles = append(cycles, component)
+			}
+		}
+	}
+
+	for _, id := range g.sortedIDs() {
+		if _, seen := indices[id]; !seen {
+			visit(id)
+		}
+	}
+	return cycles
+}
+
+// Blockers returns every issue that id transitively depends on.
+func (g *Graph) Blockers(id int) []int {
+	seen := map[int]bool{id: true}
+	queue := []int{id}
+	blockers := make([]int, 0)
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		n, ok := g.Nodes[current]
+		if !ok {
+			continue
+		}
+		for _, next := range n.BlockedOn {
+			if !seen[next] {
+				seen[next] = true
+				blockers = append(blockers, next)
+				queue = append(queue, next)
+			}
+		}
+	}
+	sort.Ints(blockers)
+	return blockers
+}
+
+// OpenBlockers returns the transitive blockers of id that are still open.
+func (g *Graph) OpenBlockers(id int) []int {
+	open := make([]int, 0)
+	for _, blocker := range g.Blockers(id) {
+		if g.Nodes[blocker].Open() {
+			open = append(open, blocker)
+		}
+	}
+	return open
+}
+
+// CriticalPath returns the longest chain of open blockers starting at id, i.e.
+// the sequence of issues that must be fixed one after another before id can
+// land. Cycles are broken at the first repeated issue.
+func (g *Graph) CriticalPath(id int) []int {
+	memo := make(map[int][]int)
+	visiting := make(map[int]bool)
+
+	var longest func(id int) []int
+	longest = func(id int) []int {
+		if path, ok := memo[id]; ok {
+			return path
+		}
+		visiting[id] = true
+		var best []int
+		for _, next := range g.Nodes[id].BlockedOn {
+			if visiting[next] || !g.Nodes[next].Open() {
+				continue
+			}
+			if path := longest(next); len(path) > len(best) {
+				best = path
+			}
+		}
+		visiting[id] = false
+		path := append([]int{id}, best...)
+		memo[id] = path
+		return path
+	}
+
+	if _, ok := g.Nodes[id]; !ok {
+		return []int{id}
+	}
+	return longest(id)
+}
+
+// dotEscaper makes text safe to use in a quoted DOT string.
+var dotEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\r", "", "\n", "\\n")
+
+func (g *Graph) WriteDOT(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, "digraph blocking {"); err != nil {
+		return err
+	}
+	for _, id := range g.sortedIDs() {
+		n := g.Nodes[id]
+		style := ""
+		switch {
+		case n.Issue == nil:
+			style = ", style=dashed"
+		case !n.Open():
+			style = ", style=filled, fillcolor=lightgrey"
+		}
+		label := strconv.Itoa(id)
+		if title := n.Title(); title != "" {
+			label += "\\n" + dotEscaper.Replace(title)
+		}
+		fmt.Fprintf(w, "  %d [label=\"%s\"%s];\n", id, label, style)
+	}
+	for _, id := range g.sortedIDs() {
+		for _, blocker := range g.Nodes[id].BlockedOn {
+			fmt.Fprintf(w, "  %d -> %d;\n", id, blocker)
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+type nodeJSON struct {
+	ID        int    `json:"id"`
+	Title     string `json:"title,omitempty"`
+	Open      bool   `json:"open"`
+	Resolved  bool   `json:"resolved"`
+	BlockedOn []int  `json:"blockedOn"`
+	Blocking  []int  `json:"blocking"`
+}
+
+func (g *Graph) MarshalJSON() ([]byte, error) {
+	nodes := make([]nodeJSON, 0, len(g.Nodes))
+	for _, id := range g.sortedIDs() {
+		n := g.Nodes[id]
+		nodes = append(nodes, nodeJSON{
+			ID:        id,
+			Title:     n.Title(),
+			Open:      n.Open(),
+			Resolved:  n.Issue != nil,
+			BlockedOn: n.BlockedOn,
+			Blocking:  n.Blocking,
+		})
+	}
+	return json.Marshal(map[string]interface{}{
+		"nodes":  nodes,
+		"cycles": g.Cycles(),
+	})
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+
+	"github.com/tbuckley/go-issuetracker/analytics"
+	"github.com/tbuckley/go-issuetracker/query"
+)
+
+func runHealth(wg *query.WorkGroup, client *http.Client, args []string) {
+	flags := flag.NewFlagSet("health", flag.ExitOnError)
+	project := flags.String("project", "chromium", "Project to check")
+	q := flags.String("query", "Cr:UI", "Search query selecting the issues")
+	milestone := flags.Int("milestone", 42, "Current milestone")
+	flags.Parse(args)
+
+	metrics, err := analytics.FetchHealthMetrics(wg.NewQuery(*project).Client(client).Query(*q), *milestone)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err.Error())
+		return
+	}
+
+	fmt.Printf("Total bugs: %v\n", metrics["total"])
+
+	fmt.Println("== Cleaning list ==")
+	fmt.Printf("Untriaged: %v\n", metrics["untriaged"])
+	fmt.Printf("No owner: %v\n", metrics["no_owner"])
+	fmt.Printf("No milestone: %v\n", metrics["no_milestone"])
+	fmt.Printf("No priority: %v\n", metrics["no_priority"])
+	fmt.Printf("No type: %v\n", metrics["no_type"])
+	fmt.Printf("No status: %v\n", metrics["no_status"])
+	fmt.Printf("No OS: %v\n", metrics["no_os"])
+	fmt.Printf("Old milestones: %v\n", metrics["old_milestone"])
+
+	fmt.Println("== Priority list ==")
+	fmt.Printf("P0: %v\n", metrics["p0"])
+	fmt.Printf("P1: %v\n", metrics["p1"])
+	fmt.Printf("M%v Launch bugs: %v\n", *milestone, metrics["launch_current"])
+	fmt.Printf("M%v Launch bugs: %v\n", *milestone+1, metrics["launch_next"])
+}
@@ -1,13 +1,19 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"time"
 
+	"github.com/tbuckley/go-issuetracker/analytics"
 	"github.com/tbuckley/go-issuetracker/common"
 	"github.com/tbuckley/go-issuetracker/gcode"
 	"github.com/tbuckley/go-issuetracker/googauth"
+	"github.com/tbuckley/go-issuetracker/offline"
 	"github.com/tbuckley/go-issuetracker/query"
 )
 
@@ -15,6 +21,10 @@ var (
 	fSecretsFile = flag.String("secrets", "", "Oauth secrets")
 	fStorageFile = flag.String("storage", "", "Oauth storage")
 	fLabel       = flag.String("label", "cr-ui-settings", "Label to filter")
+	fRate        = flag.Float64("rate", 5, "Maximum requests per second to the tracker (0 for no limit)")
+	fBurst       = flag.Int("burst", 10, "Maximum burst of requests to the tracker")
+	fConcurrency = flag.Int("concurrency", 0, "Maximum requests in flight to the tracker (0 for no limit)")
+	fSource      = flag.String("source", "", "Read issues from a dump instead of the tracker, e.g. file:dump.jsonl")
 )
 
 func DisplayGroupsByIntProperty(issues []*gcode.Issue, propFunc common.IntPropertyFunc) {
@@ -35,31 +45,86 @@ func DisplayGroupsByStringProperty(issues []*gcode.Issue, propFunc common.String
 func main() {
 	flag.Parse()
 
-	if *fStorageFile == "" || *fSecretsFile == "" {
-		fmt.Println("Usage: ./go-issuetracker --secrets=SECRETFILE --storage=STORAGEFILE")
-		return
+	wg := query.NewWorkGroup(20)
+	limiter := query.NewRateLimiter(*fRate, *fBurst)
+	limiter.SetConcurrency(*fConcurrency)
+	wg.SetRateLimiter(limiter)
+	client := http.DefaultClient
+	if *fSource != "" {
+		source, err := offline.ParseSpec(*fSource)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err.Error())
+			return
+		}
+		wg.SetSource(source)
+	} else {
+		if *fStorageFile == "" || *fSecretsFile == "" {
+			fmt.Println("Usage: ./go-issuetracker (--secrets=SECRETFILE --storage=STORAGEFILE | --source=file:DUMP) [report|bulk|search|dupes|export|show|graph|health|serve]")
+			return
+		}
+
+		var err error
+		client, err = googauth.Authenticate(*fStorageFile, *fSecretsFile)
+		if err != nil {
+			panic(err)
+		}
 	}
 
-	client, err := googauth.Authenticate(*fStorageFile, *fSecretsFile)
-	if err != nil {
-		panic(err)
+	switch flag.Arg(0) {
+	case "", "report":
+		runReport(wg, client)
+	case "bulk":
+		runBulk(wg, client, flag.Args()[1:])
+	case "search":
+		runSearch(wg, client, flag.Args()[1:])
+	case "dupes":
+		runDupes(wg, client, flag.Args()[1:])
+	case "export":
+		runExport(wg, client, flag.Args()[1:])
+	case "graph":
+		runGraph(wg, client, flag.Args()[1:])
+	case "show":
+		runShow(wg, client, flag.Args()[1:])
+	case "health":
+		runHealth(wg, client, flag.Args()[1:])
+	case "serve":
+		runServe(wg, client, flag.Args()[1:])
+	default:
+		fmt.Printf("Unknown command: %v\n", flag.Arg(0))
 	}
+}
 
+func runReport(wg *query.WorkGroup, client *http.Client) {
 	log.Println("Starting requests...")
 
-	wg := query.NewWorkGroup(20)
 	q := wg.NewQuery("chromium").Client(client)
 	// q = q.Label(*fLabel)
-	q = q.Query("Cr:UI")
-
-	issues := make([]*gcode.Issue, 0)
-	issueChan := q.FetchAllIssues()
-	for issue := range issueChan {
-		if issue.Error != nil {
-			fmt.Printf("Error: %v\n", issue.Error.Error())
-			return
+	q = q.Query("Cr:UI").InOrder().CheckTotal().Partial()
+
+	// Replies are needed for the time to triage and SLA sections
+	issues, fetchErr := query.CollectIssues(q.FetchAllIssuesWithReplies())
+	var incomplete *query.IncompleteError
+	var mismatch *query.TotalMismatchError
+	if errors.As(fetchErr, &incomplete) {
+		if len(incomplete.Pages) > 0 {
+			fmt.Printf("*** INCOMPLETE DATA: %v pages could not be fetched ***\n", len(incomplete.Pages))
+			for _, page := range incomplete.Pages {
+				fmt.Printf("  offset %v: %v\n", page.Offset, page.Error.Error())
+			}
+		}
+		if len(incomplete.Replies) > 0 {
+			fmt.Printf("*** INCOMPLETE TIMINGS: replies of %v issues could not be fetched ***\n", len(incomplete.Replies))
+			for _, replies := range incomplete.Replies {
+				fmt.Printf("  crbug.com/%v: %v\n", replies.IssueID, replies.Error.Error())
+			}
 		}
-		issues = append(issues, issue.Issue)
+	}
+	if errors.As(fetchErr, &mismatch) {
+		fmt.Printf("*** INCOMPLETE DATA: fetched %v of %v issues ***\n", mismatch.Fetched, mismatch.Expected)
+	}
+	if fetchErr != nil && incomplete == nil && mismatch == nil {
+		fmt.Printf("Error: %v\n", fetchErr.Error())
+		return
 	}
 	fmt.Printf("Found: %v\n", len(issues))
 
@@ -69,12 +134,22 @@ func main() {
 	ownerGroups := common.GroupStringProperty(issues, common.GetIssueOwner)
 	typeGroups := common.GroupStringProperty(issues, common.GetIssueType)
 	statusGroups := common.GroupStringProperty(issues, common.GetIssueStatus)
-	osGroups := common.GroupStringProperty(issues, common.GetIssueOS)
+	osGroups := common.GroupStringListProperty(issues, common.GetIssueOSList)
 	publishedGroups := common.GroupTimeProperty(issues, common.GetIssuePublished)
 	updatedGroups := common.GroupTimeProperty(issues, common.GetIssueUpdated)
 
 	fmt.Printf("Total bugs: %v\n", len(issues))
 
+	fmt.Println("== Created vs. resolved ==")
+	weeks := common.WeekBuckets(time.UTC)
+	trendEnd := weeks.Next(weeks.Start(time.Now()))
+	trend, err := analytics.FetchCreatedVsResolved(q, weeks, trendEnd.AddDate(0, 0, -7*12), trendEnd)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err.Error())
+	} else {
+		trend.WriteText(os.Stdout)
+	}
+
 	fmt.Println("== Cleaning list ==")
 	fmt.Printf("Untriaged: %v\n", len(statusGroups.Groups["Untriaged"]))
 	fmt.Printf("No owner: %v\n", len(ownerGroups.None))
@@ -96,6 +171,22 @@ func main() {
 	nextMilestoneLaunchBugs := LaunchBugsForMilestone(milestoneGroups, currentMilestone+1)
 	fmt.Printf("M%v Launch bugs: %v\n", currentMilestone+1, len(nextMilestoneLaunchBugs))
 
+	fmt.Println("== Priority by milestone ==")
+	priorityByMilestone := common.CrossTab(issues,
+		common.IntProperty("Pri", common.GetIssuePriority),
+		common.IntProperty("M", common.GetIssueMilestone))
+	priorityByMilestone.WriteText(os.Stdout)
+
+	fmt.Println("== Time to triage by priority ==")
+	timings := analytics.GetAllIssueTimings(issues, time.Now())
+	triageByPriority := analytics.SummarizeBy(timings, common.IntProperty("Pri", common.GetIssuePriority))
+	analytics.WriteTimingSummaries(os.Stdout, triageByPriority, analytics.TimeToTriage)
+	analytics.WriteSLAResults(os.Stdout, analytics.EvaluateSLAs(timings, []*analytics.SLA{
+		{Name: "P0 triaged within 1 day", Priorities: []int{0}, Metric: analytics.TimeToTriage, Within: 24 * time.Hour},
+		{Name: "P1 triaged within 2 days", Priorities: []int{1}, Metric: analytics.TimeToTriage, Within: 48 * time.Hour},
+		{Name: "P1 owned within 7 days", Priorities: []int{1}, Metric: analytics.TimeToOwner, Within: 7 * 24 * time.Hour},
+	}))
+
 	fmt.Println("== Superlatives list ==")
 	mostStarred := GetMostStarredIssue(starGroups)
 	fmt.Printf("Top stars: crbug.com/%v (%v)\n", mostStarred.ID, mostStarred.Stars)
@@ -103,6 +194,10 @@ func main() {
 	fmt.Printf("Oldest published: crbug.com/%v (%v)\n", lastPublished.ID, lastPublished.Published)
 	lastUpdated := GetOldestIssue(updatedGroups)
 	fmt.Printf("Oldest updated: crbug.com/%v (%v)\n", lastUpdated.ID, lastUpdated.Published)
+
+	if fetchErr != nil {
+		fmt.Println("*** INCOMPLETE DATA: the numbers above are missing some issues ***")
+	}
 }
 
 func GetOldMilestoneIssues(milestoneGroups *common.IntGroups, milestone int) []*gcode.Issue {
@@ -0,0 +1,223 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tbuckley/go-issuetracker/common"
+	"github.com/tbuckley/go-issuetracker/gcode"
+)
+
+type Type string
+
+const (
+	Gauge   Type = "gauge"
+	Counter Type = "counter"
+)
+
+type Sample struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  float64           `json:"value"`
+}
+
+// Family is a metric and its samples, written in the Prometheus text format.
+type Family struct {
+	Name    string    `json:"name"`
+	Help    string    `json:"help"`
+	Type    Type      `json:"type"`
+	Samples []*Sample `json:"samples"`
+}
+
+func NewFamily(name string, metricType Type, help string) *Family {
+	return &Family{Name: name, Help: help, Type: metricType, Samples: make([]*Sample, 0)}
+}
+
+// Add records a sample. Labels are given as name, value pairs.
+func (f *Family) Add(value float64, labels ...string) *Family {
+	sample := &Sample{Value: value}
+	if len(labels) > 0 {
+		sample.Labels = make(map[string]string, len(labels)/2)
+		for i := 0; i+1 < len(labels); i += 2 {
+			sample.Labels[labels[i]] = labels[i+1]
+		}
+	}
+	f.Samples = append(f.Samples, sample)
+	return f
+}
+
+var labelEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func (f *Family) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "# HELP %v %v\n", f.Name, strings.Replace(f.Help, "\n", " ", -1))
+	fmt.Fprintf(w, "# TYPE %v %v\n", f.Name, f.Type)
+	for _, sample := range f.Samples {
+		names := make([]string, 0, len(sample.Labels))
+		for name := range sample.Labels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		pairs := make([]string, len(names))
+		for i, name := range names {
+			pairs[i] = name + "=\"" + labelEscaper.Replace(sample.Labels[name]) + "\""
+		}
+
+		labels := ""
+		if len(pairs) > 0 {
+			labels = "{" + strings.Join(pairs, ",") + "}"
+		}
+		if _, err := fmt.Fprintf(w, "%v%v %v\n", f.Name, labels, formatValue(sample.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func WriteText(w io.Writer, families []*Family) error {
+	for _, family := range families {
+		if err := family.WriteText(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countBy counts issues by each value of the named field, using "none" for
+// issues without one.
+func countBy(family *Family, issues []*gcode.Issue, fieldName string, label string) *Family {
+	field, ok := common.LookupField(fieldName)
+	if !ok {
+		return family
+	}
+	counts := make(map[string]int)
+	for _, issue := range issues {
+		values := field.Values(issue)
+		if len(values) == 0 {
+			values = []string{"none"}
+		}
+		for _, value := range values {
+			counts[value]++
+		}
+	}
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		family.Add(float64(counts[key]), label, key)
+	}
+	return family
+}
+
+// IssueFamilies computes gauges over the open issues in issues.
+func IssueFamilies(issues []*gcode.Issue) []*Family {
+	open := make([]*gcode.Issue, 0, len(issues))
+	for _, issue := range issues {
+		if issue.State != "closed" {
+			open = append(open, issue)
+		}
+	}
+
+	untriaged := 0
+	for _, issue := range open {
+		if issue.Status == "Untriaged" {
+			untriaged++
+		}
+	}
+	missingFamily := NewFamily("issuetracker_open_issues_missing", Gauge, "Open issues without a value for a field.")
+	for _, name := range []string{"milestone", "os", "owner", "priority", "status", "type"} {
+		field, ok := common.LookupField(name)
+		if !ok {
+			continue
+		}
+		missing := 0
+		for _, issue := range open {
+			if len(field.Values(issue)) == 0 {
+				missing++
+			}
+		}
+		missingFamily.Add(float64(missing), "field", name)
+	}
+
+	return []*Family{
+		NewFamily("issuetracker_open_issues", Gauge, "Open issues.").Add(float64(len(open))),
+		countBy(NewFamily("issuetracker_open_issues_by_priority", Gauge, "Open issues by priority."), open, "priority", "priority"),
+		countBy(NewFamily("issuetracker_open_issues_by_milestone", Gauge, "Open issues by milestone."), open, "milestone", "milestone"),
+		countBy(NewFamily("issuetracker_open_issues_by_status", Gauge, "Open issues by status."), open, "status", "status"),
+		countBy(NewFamily("issuetracker_open_issues_by_component", Gauge, "Open issues by component."), open, "components", "component"),
+		NewFamily("issuetracker_untriaged_issues", Gauge, "Open issues with status Untriaged.").Add(float64(untriaged)),
+		missingFamily,
+	}
+}
+
+// SyncStatus tracks the health of the periodic sync with the tracker.
+type SyncStatus struct {
+	LastAttempt time.Time
+	LastSuccess time.Time
+	Duration    time.Duration
+	Errors      int
+	Issues      int
+}
+
+func (s *SyncStatus) Success(start time.Time, now time.Time, issues int) {
+	s.LastAttempt = start
+	s.LastSuccess = now
+	s.Duration = now.Sub(start)
+	s.Issues = issues
+}
+
+func (s *SyncStatus) Failure(start time.Time, now time.Time) {
+	s.LastAttempt = start
+	s.Duration = now.Sub(start)
+	s.Errors++
+}
+
+func timestamp(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+func (s *SyncStatus) Families() []*Family {
+	return []*Family{
+		NewFamily("issuetracker_sync_last_success_timestamp_seconds", Gauge, "Time of the last successful sync.").Add(timestamp(s.LastSuccess)),
+		NewFamily("issuetracker_sync_last_attempt_timestamp_seconds", Gauge, "Time the last sync started.").Add(timestamp(s.LastAttempt)),
+		NewFamily("issuetracker_sync_duration_seconds", Gauge, "Duration of the last sync.").Add(s.Duration.Seconds()),
+		NewFamily("issuetracker_sync_errors_total", Counter, "Failed syncs.").Add(float64(s.Errors)),
+		NewFamily("issuetracker_sync_issues", Gauge, "Issues fetched by the last successful sync.").Add(float64(s.Issues)),
+	}
+}
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Handler serves the families returned by collect on every scrape.
+func Handler(collect func(r *http.Request) ([]*Family, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		families, err := collect(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		WriteText(w, families)
+	})
+}
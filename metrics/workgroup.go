@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/tbuckley/go-issuetracker/query"
+)
+
+// WorkGroupFamilies exports a work group's stats.
+func WorkGroupFamilies(stats query.Stats) []*Family {
+	tasks := NewFamily("issuetracker_workgroup_tasks_total", Counter, "Finished tasks.")
+	failed := NewFamily("issuetracker_workgroup_task_failures_total", Counter, "Failed tasks.")
+	retries := NewFamily("issuetracker_workgroup_task_retries_total", Counter, "Task retries.")
+	bytes := NewFamily("issuetracker_workgroup_response_bytes_total", Counter, "Bytes read from responses.")
+	duration := NewFamily("issuetracker_workgroup_task_duration_seconds_total", Counter, "Time spent running tasks.")
+	wait := NewFamily("issuetracker_workgroup_task_wait_seconds_total", Counter, "Time tasks spent queued.")
+	maxDuration := NewFamily("issuetracker_workgroup_task_duration_seconds_max", Gauge, "Longest task.")
+
+	kinds := make([]string, 0, len(stats.Kinds))
+	for kind := range stats.Kinds {
+		kinds = append(kinds, string(kind))
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		s := stats.Kinds[query.TaskKind(kind)]
+		tasks.Add(float64(s.Tasks), "kind", kind)
+		failed.Add(float64(s.Failed), "kind", kind)
+		retries.Add(float64(s.Retries), "kind", kind)
+		bytes.Add(float64(s.Bytes), "kind", kind)
+		duration.Add(s.Duration.Seconds(), "kind", kind)
+		wait.Add(s.Wait.Seconds(), "kind", kind)
+		maxDuration.Add(s.MaxDuration.Seconds(), "kind", kind)
+	}
+
+	responses := NewFamily("issuetracker_workgroup_responses_total", Counter, "Responses by status code.")
+	codes := make([]int, 0, len(stats.StatusCodes))
+	for code := range stats.StatusCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		responses.Add(float64(stats.StatusCodes[code]), "code", strconv.Itoa(code))
+	}
+
+	return []*Family{
+		NewFamily("issuetracker_workgroup_workers", Gauge, "Workers.").Add(float64(stats.Workers)),
+		NewFamily("issuetracker_workgroup_queued_tasks", Gauge, "Tasks waiting for a worker.").Add(float64(stats.Queued)),
+		NewFamily("issuetracker_workgroup_running_tasks", Gauge, "Tasks being run.").Add(float64(stats.Running)),
+		tasks, failed, retries, bytes, duration, wait, maxDuration, responses,
+	}
+}
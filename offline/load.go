@@ -0,0 +1,182 @@
+package offline
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tbuckley/go-issuetracker/export"
+	"github.com/tbuckley/go-issuetracker/gcode"
+)
+
+const trackerTimeLayout = "2006-01-02T15:04:05.000Z"
+
+// Load reads issues from a dump, picking the format from the extension:
+// .jsonl or .json for whole issues as written by a JSONL export, .csv for a
+// CSV export and .xml or .atom for issue feeds saved from the tracker.
+func Load(path string) ([]*gcode.Issue, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".json":
+		return ReadJSONL(f)
+	case ".csv":
+		return ReadCSV(f)
+	case ".xml", ".atom":
+		return ReadAtom(f)
+	}
+	return nil, fmt.Errorf("Unknown dump format: %v", path)
+}
+
+func ReadJSONL(r io.Reader) ([]*gcode.Issue, error) {
+	issues := make([]*gcode.Issue, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		issue := new(gcode.Issue)
+		if err := json.Unmarshal(scanner.Bytes(), issue); err != nil {
+			return nil, fmt.Errorf("line %v: %v", line, err)
+		}
+		issues = append(issues, issue)
+	}
+	return issues, scanner.Err()
+}
+
+// ReadAtom reads one or more concatenated issue feeds.
+func ReadAtom(r io.Reader) ([]*gcode.Issue, error) {
+	issues := make([]*gcode.Issue, 0)
+	decoder := xml.NewDecoder(r)
+	for {
+		feed := new(gcode.IssuesFeed)
+		err := decoder.Decode(feed)
+		if err == io.EOF {
+			return issues, nil
+		} else if err != nil {
+			return nil, err
+		}
+		issues = append(issues, feed.Issues...)
+	}
+}
+
+// labelColumns rebuild labels from the columns of a CSV export.
+var labelColumns = map[string]string{
+	"priority":   "Pri-",
+	"milestone":  "M-",
+	"type":       "Type-",
+	"os":         "OS-",
+	"components": "Cr-",
+	"labels":     "",
+}
+
+// trackerTime converts a date written by an export back to the tracker's
+// layout.
+func trackerTime(value string) string {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t.Format(trackerTimeLayout)
+	}
+	return value
+}
+
+// ReadCSV reads a CSV export. Columns that can't be mapped back onto an
+// issue, such as age, are ignored.
+func ReadCSV(r io.Reader) ([]*gcode.Issue, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return make([]*gcode.Issue, 0), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	issues := make([]*gcode.Issue, 0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return issues, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		issue := new(gcode.Issue)
+		seen := make(map[string]bool)
+		addLabel := func(label string) {
+			if !seen[strings.ToLower(label)] {
+				seen[strings.ToLower(label)] = true
+				issue.Labels = append(issue.Labels, label)
+			}
+		}
+		for i, value := range record {
+			if i >= len(header) || value == "" {
+				continue
+			}
+			column := strings.ToLower(header[i])
+			values := strings.Split(value, export.DefaultSeparator)
+
+			if prefix, ok := labelColumns[column]; ok {
+				for _, v := range values {
+					addLabel(prefix + v)
+				}
+				continue
+			}
+			if strings.HasPrefix(column, "label:") {
+				for _, v := range values {
+					addLabel(header[i][len("label:"):] + v)
+				}
+				continue
+			}
+
+			switch column {
+			case "id":
+				if issue.ID, err = strconv.Atoi(value); err != nil {
+					return nil, fmt.Errorf("Invalid id in CSV: %v", value)
+				}
+			case "stars":
+				issue.Stars, _ = strconv.Atoi(value)
+			case "title":
+				issue.Title = value
+			case "content":
+				issue.Content = value
+			case "status":
+				issue.Status = value
+			case "state":
+				issue.State = value
+			case "owner":
+				issue.Owner = value
+			case "reporter":
+				issue.Author = value
+			case "cc":
+				issue.CCs = values
+			case "published":
+				issue.Published = trackerTime(value)
+			case "updated":
+				issue.Updated = trackerTime(value)
+			case "closed":
+				issue.Closed = trackerTime(value)
+			}
+		}
+		if issue.State == "" {
+			issue.State = "open"
+			if issue.Closed != "" {
+				issue.State = "closed"
+			}
+		}
+		issues = append(issues, issue)
+	}
+}
@@ -0,0 +1,175 @@
+package offline
+
+import (
+	"bytes"
+	"net/url"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/tbuckley/go-issuetracker/export"
+	"github.com/tbuckley/go-issuetracker/gcode"
+	"github.com/tbuckley/go-issuetracker/query"
+)
+
+func offlineIssue(id int, state, updated string, labels ...string) *gcode.Issue {
+	issue := &gcode.Issue{ID: id, State: state, Status: "Assigned", Owner: "a@chromium.org", Labels: labels}
+	issue.Title = "Issue"
+	issue.Published = "2015-02-01T10:00:00.000Z"
+	issue.Updated = updated
+	if state == "closed" {
+		issue.Closed = updated
+	}
+	return issue
+}
+
+func testIssues() []*gcode.Issue {
+	return []*gcode.Issue{
+		offlineIssue(3, "open", "2015-02-10T10:00:00.000Z", "Pri-1", "Cr-UI"),
+		offlineIssue(1, "closed", "2015-02-05T10:00:00.000Z", "Pri-2", "Cr-UI-Settings", "M-42"),
+		offlineIssue(2, "open", "2015-02-12T10:00:00.000Z", "Pri-2", "Cr-Blink", "OS-Mac"),
+	}
+}
+
+func exportAll(t *testing.T, format export.Format, columns []string) *bytes.Buffer {
+	issues := testIssues()
+	c := make(chan query.OptionalIssue, len(issues))
+	for _, issue := range issues {
+		c <- query.OptionalIssue{Issue: issue}
+	}
+	close(c)
+	buf := new(bytes.Buffer)
+	if _, err := export.Export(buf, c, export.Options{Format: format, Columns: columns}); err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestJSONLRoundTrip(t *testing.T) {
+	issues, err := ReadJSONL(exportAll(t, export.JSONL, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(issues, testIssues()) {
+		t.Errorf("round trip changed the issues")
+	}
+}
+
+func sorted(labels []string) []string {
+	labels = append([]string(nil), labels...)
+	sort.Strings(labels)
+	return labels
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	columns := []string{"id", "title", "status", "state", "owner", "priority", "milestone", "components", "os", "labels", "updated", "closed"}
+	issues, err := ReadCSV(exportAll(t, export.CSV, columns))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := testIssues()
+	if len(issues) != len(want) {
+		t.Fatalf("got %v issues, want %v", len(issues), len(want))
+	}
+	for i, issue := range issues {
+		w := want[i]
+		if issue.ID != w.ID || issue.Title != w.Title || issue.Status != w.Status ||
+			issue.State != w.State || issue.Owner != w.Owner {
+			t.Errorf("issue %v = %+v, want %+v", i, issue, w)
+		}
+		// Labels come back grouped by column
+		if !reflect.DeepEqual(sorted(issue.Labels), sorted(w.Labels)) {
+			t.Errorf("labels of %v = %v, want %v", issue.ID, issue.Labels, w.Labels)
+		}
+		// CSV exports keep only the date
+		if issue.Updated[:10] != w.Updated[:10] {
+			t.Errorf("updated of %v = %v, want %v", issue.ID, issue.Updated, w.Updated)
+		}
+	}
+}
+
+func TestReadCSVEmpty(t *testing.T) {
+	issues, err := ReadCSV(bytes.NewBufferString(""))
+	if err != nil || len(issues) != 0 {
+		t.Errorf("ReadCSV of nothing = %v, %v", issues, err)
+	}
+}
+
+func fetchIDs(t *testing.T, s *Source, values url.Values) (int, []int) {
+	feed, err := s.FetchPage("chromium", values)
+	if err != nil {
+		t.Fatalf("FetchPage(%v): %v", values.Encode(), err)
+	}
+	ids := make([]int, 0)
+	for _, issue := range feed.Issues {
+		ids = append(ids, issue.ID)
+	}
+	return feed.TotalResults, ids
+}
+
+func TestSourceFiltering(t *testing.T) {
+	s := NewSource(testIssues())
+	tests := []struct {
+		values url.Values
+		want   []int
+	}{
+		{url.Values{}, []int{1, 2, 3}},
+		{url.Values{"can": {"all"}}, []int{1, 2, 3}},
+		{url.Values{"can": {"open"}}, []int{2, 3}},
+		{url.Values{"label": {"Pri-2"}}, []int{1, 2}},
+		{url.Values{"can": {"open"}, "label": {"Pri-2"}}, []int{2}},
+		{url.Values{"updated-min": {"2015-02-10T00:00:00Z"}}, []int{2, 3}},
+		{url.Values{"q": {"Cr:UI"}}, []int{1, 3}},
+	}
+	for _, test := range tests {
+		total, ids := fetchIDs(t, s, test.values)
+		if total != len(test.want) || !reflect.DeepEqual(ids, test.want) {
+			t.Errorf("%v: got %v of %v, want %v", test.values.Encode(), ids, total, test.want)
+		}
+	}
+
+	for _, values := range []url.Values{
+		{"can": {"new"}},
+		{"updated-min": {"yesterday"}},
+		{"q": {"a OR"}},
+	} {
+		if _, err := s.FetchPage("chromium", values); err == nil {
+			t.Errorf("%v: got no error", values.Encode())
+		}
+	}
+}
+
+func TestSourcePaging(t *testing.T) {
+	issues := make([]*gcode.Issue, 0)
+	for id := 60; id >= 1; id-- {
+		issues = append(issues, offlineIssue(id, "open", ""))
+	}
+	s := NewSource(issues)
+
+	tests := []struct {
+		start, limit string
+		first, count int
+	}{
+		{"1", "25", 1, 25},
+		{"26", "25", 26, 25},
+		{"51", "25", 51, 10},
+		{"61", "25", 0, 0},
+		{"", "", 1, 25},
+		{"1", "0", 1, 25},
+	}
+	for _, test := range tests {
+		values := url.Values{"start-index": {test.start}, "max-results": {test.limit}}
+		total, ids := fetchIDs(t, s, values)
+		if total != 60 || len(ids) != test.count || (test.count > 0 && ids[0] != test.first) {
+			t.Errorf("start %q limit %q: got %v of %v", test.start, test.limit, ids, total)
+		}
+	}
+
+	// Crawling through a work group fetches every issue once
+	wg := query.NewWorkGroup(2)
+	wg.SetSource(s)
+	fetched, err := query.CollectIssues(wg.NewQuery("chromium").FetchAllIssues())
+	if err != nil || len(fetched) != 60 {
+		t.Errorf("crawl = %v issues, %v; want 60", len(fetched), err)
+	}
+}
@@ -0,0 +1,129 @@
+package offline
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tbuckley/go-issuetracker/common"
+	"github.com/tbuckley/go-issuetracker/filter"
+	"github.com/tbuckley/go-issuetracker/gcode"
+)
+
+// Source answers queries from issues held in memory, supporting can=open and
+// can=all, label, updated-min and the search terms understood by the filter
+// package, which covers the date ranges and free text (as substrings) used
+// by the query builders.
+type Source struct {
+	Issues []*gcode.Issue
+}
+
+func NewSource(issues []*gcode.Issue) *Source {
+	sorted := append([]*gcode.Issue(nil), issues...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+	return &Source{Issues: sorted}
+}
+
+func Open(path string) (*Source, error) {
+	issues, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewSource(issues), nil
+}
+
+func (s *Source) match(values url.Values) (func(issue *gcode.Issue) bool, error) {
+	var open bool
+	switch values.Get("can") {
+	case "", "all":
+	case "open":
+		open = true
+	default:
+		return nil, fmt.Errorf("Unsupported can in offline source: %v", values.Get("can"))
+	}
+
+	f, err := filter.Parse(values.Get("q"))
+	if err != nil {
+		return nil, err
+	}
+	var labelFilter *filter.Filter
+	if label := values.Get("label"); label != "" {
+		if labelFilter, err = filter.Parse("label:" + label); err != nil {
+			return nil, err
+		}
+	}
+
+	var updatedMin time.Time
+	if value := values.Get("updated-min"); value != "" {
+		if updatedMin, err = time.Parse(time.RFC3339, value); err != nil {
+			return nil, fmt.Errorf("Invalid updated-min: %v", value)
+		}
+	}
+
+	return func(issue *gcode.Issue) bool {
+		if open && issue.State == "closed" {
+			return false
+		}
+		if !updatedMin.IsZero() {
+			if updated, ok := common.GetIssueUpdated(issue); !ok || updated.Before(updatedMin) {
+				return false
+			}
+		}
+		if labelFilter != nil && !labelFilter.Match(issue) {
+			return false
+		}
+		return f.Match(issue)
+	}, nil
+}
+
+func intParam(values url.Values, key string, fallback int) int {
+	value, err := strconv.Atoi(values.Get(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func (s *Source) FetchPage(project string, values url.Values) (*gcode.IssuesFeed, error) {
+	match, err := s.match(values)
+	if err != nil {
+		return nil, err
+	}
+	matched := make([]*gcode.Issue, 0)
+	for _, issue := range s.Issues {
+		if match(issue) {
+			matched = append(matched, issue)
+		}
+	}
+
+	start := intParam(values, "start-index", 1)
+	limit := intParam(values, "max-results", 25)
+	if limit <= 0 {
+		limit = 25
+	}
+	feed := &gcode.IssuesFeed{Issues: make([]*gcode.Issue, 0)}
+	feed.TotalResults = len(matched)
+	feed.StartIndex = start
+	feed.ItemsPerPage = limit
+	if start >= 1 && start <= len(matched) {
+		end := start - 1 + limit
+		if end > len(matched) {
+			end = len(matched)
+		}
+		feed.Issues = matched[start-1 : end]
+	}
+	return feed, nil
+}
+
+// ParseSpec opens a source given as "file:path", as accepted by --source.
+func ParseSpec(spec string) (*Source, error) {
+	if !strings.HasPrefix(spec, "file:") {
+		return nil, fmt.Errorf("Unknown source: %v", spec)
+	}
+	return Open(spec[len("file:"):])
+}
@@ -0,0 +1,68 @@
+package query
+
+import (
+	"sort"
+)
+
+// Count returns the number of issues matching the query without fetching
+// them, by requesting a single issue and reading the total.
+func (q *Query) Count() (int, error) {
+	feed, err := q.Offset(0).Limit(1).FetchPage()
+	if err != nil {
+		return 0, err
+	}
+	return feed.TotalResults, nil
+}
+
+func (q *Query) WorkGroup() *WorkGroup {
+	return q.workGroup
+}
+
+// FetchPages fetches one page of each query concurrently. Results are in the
+// order of queries.
+func (g *WorkGroup) FetchPages(queries []*Query) []OptionalIssuesFeed {
+	return <-g.addQueryTasks(queries)
+}
+
+// Counts counts the issues matching each query concurrently. Failed counts
+// are left at zero and the first error is returned.
+func (g *WorkGroup) Counts(queries []*Query) ([]int, error) {
+	countQueries := make([]*Query, len(queries))
+	for i, q := range queries {
+		countQueries[i] = q.Offset(0).Limit(1)
+	}
+
+	counts := make([]int, len(queries))
+	var err error
+	for i, result := range g.FetchPages(countQueries) {
+		if result.Error != nil {
+			if err == nil {
+				err = result.Error
+			}
+			continue
+		}
+		counts[i] = result.IssuesFeed.TotalResults
+	}
+	return counts, err
+}
+
+// CountAll is Counts for named queries.
+func (g *WorkGroup) CountAll(queries map[string]*Query) (map[string]int, error) {
+	names := make([]string, 0, len(queries))
+	for name := range queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	list := make([]*Query, len(names))
+	for i, name := range names {
+		list[i] = queries[name]
+	}
+	counts, err := g.Counts(list)
+
+	result := make(map[string]int, len(names))
+	for i, name := range names {
+		result[name] = counts[i]
+	}
+	return result, err
+}
@@ -0,0 +1,174 @@
+package query
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/tbuckley/go-issuetracker/gcode"
+)
+
+// maxIDsPerQuery keeps the id: search term of a batch within URL limits.
+const maxIDsPerQuery = 50
+
+type NotFoundError struct {
+	Project string
+	IDs     []int
+}
+
+func (e *NotFoundError) Error() string {
+	ids := make([]string, len(e.IDs))
+	for i, id := range e.IDs {
+		ids[i] = strconv.Itoa(id)
+	}
+	return fmt.Sprintf("Issues not found in %v: %v", e.Project, strings.Join(ids, ", "))
+}
+
+// Issues fetches specific issues by ID, open or closed. Like Query, every
+// method returns a modified copy.
+type Issues struct {
+	query   *Query
+	replies bool
+}
+
+func newIssues(project string, workGroup *WorkGroup) *Issues {
+	return &Issues{query: newQuery(project, workGroup).All()}
+}
+
+func (i *Issues) clone() *Issues {
+	clone := *i
+	return &clone
+}
+
+func (i *Issues) Client(client *http.Client) *Issues {
+	clone := i.clone()
+	clone.query = i.query.Client(client)
+	return clone
+}
+
+func (i *Issues) Server(server string) *Issues {
+	clone := i.clone()
+	clone.query = i.query.Server(server)
+	return clone
+}
+
+func (i *Issues) Priority(priority Priority) *Issues {
+	clone := i.clone()
+	clone.query = i.query.Priority(priority)
+	return clone
+}
+
+// Replies fills in the replies of every fetched issue.
+func (i *Issues) Replies() *Issues {
+	clone := i.clone()
+	clone.replies = true
+	return clone
+}
+
+func (i *Issues) Get(id int) (*gcode.Issue, error) {
+	issues, err := i.GetAll([]int{id})
+	if err != nil {
+		return nil, err
+	}
+	return issues[0], nil
+}
+
+// GetAll fetches the issues in batches through the work group and returns
+// them in the order of ids, without duplicates. If some issues don't exist,
+// the others are returned along with a *NotFoundError.
+func (i *Issues) GetAll(ids []int) ([]*gcode.Issue, error) {
+	seen := make(map[int]bool)
+	unique := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	queries := make([]*Query, 0)
+	for start := 0; start < len(unique); start += maxIDsPerQuery {
+		end := start + maxIDsPerQuery
+		if end > len(unique) {
+			end = len(unique)
+		}
+		batch := make([]string, end-start)
+		for j, id := range unique[start:end] {
+			batch[j] = strconv.Itoa(id)
+		}
+		queries = append(queries, i.query.Query("id:"+strings.Join(batch, ",")).Limit(len(batch)))
+	}
+
+	byID := make(map[int]*gcode.Issue)
+	for _, result := range i.query.workGroup.FetchPages(queries) {
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		for _, issue := range result.IssuesFeed.Issues {
+			byID[issue.ID] = issue
+		}
+	}
+
+	issues := make([]*gcode.Issue, 0, len(unique))
+	missing := make([]int, 0)
+	for _, id := range unique {
+		if issue, ok := byID[id]; ok {
+			issues = append(issues, issue)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	if i.replies && i.query.workGroup.source == nil {
+		if err := i.fetchReplies(issues); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(missing) > 0 {
+		return issues, &NotFoundError{Project: i.query.project, IDs: missing}
+	}
+	return issues, nil
+}
+
+func (i *Issues) fetchReplies(issues []*gcode.Issue) error {
+	q := i.query
+	errs := make([]error, len(issues))
+	wg := new(sync.WaitGroup)
+	for j, issue := range issues {
+		wg.Add(1)
+		go func(j int, issue *gcode.Issue) {
+			defer wg.Done()
+			replies, err := q.workGroup.NewReplies(q.project, issue.ID).Client(q.client).Server(q.server).Priority(q.priority).FetchAll()
+			if err != nil {
+				errs[j] = err
+				return
+			}
+			issue.Replies = replies
+		}(j, issue)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *WorkGroup) NewIssues(project string) *Issues {
+	return newIssues(project, g)
+}
+
+// GetIssue fetches a single issue, returning a *NotFoundError if it doesn't
+// exist.
+func (g *WorkGroup) GetIssue(project string, id int) (*gcode.Issue, error) {
+	return g.NewIssues(project).Get(id)
+}
+
+func (g *WorkGroup) GetIssues(project string, ids []int) ([]*gcode.Issue, error) {
+	return g.NewIssues(project).GetAll(ids)
+}
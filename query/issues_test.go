@@ -0,0 +1,65 @@
+package query
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestGetAllBatchesAndKeepsOrder(t *testing.T) {
+	tracker := newFakeTracker(200)
+	server := httptest.NewServer(tracker)
+	defer server.Close()
+
+	// Duplicates are dropped and the order of ids is kept
+	ids := make([]int, 0)
+	for id := 120; id >= 1; id-- {
+		ids = append(ids, id)
+	}
+	ids = append(ids, 7, 120)
+
+	issues, err := NewWorkGroup(4).NewIssues("chromium").Server(server.URL).GetAll(ids)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	got := make([]int, len(issues))
+	for i, issue := range issues {
+		got[i] = issue.ID
+	}
+	if !reflect.DeepEqual(got, ids[:120]) {
+		t.Errorf("got issues %v, want %v", got, ids[:120])
+	}
+	// 120 ids take three batches of at most maxIDsPerQuery
+	if tracker.requests != 3 {
+		t.Errorf("made %v requests, want 3", tracker.requests)
+	}
+}
+
+func TestGetAllReportsMissingIssues(t *testing.T) {
+	tracker := newFakeTracker(10)
+	server := httptest.NewServer(tracker)
+	defer server.Close()
+
+	fetch := NewWorkGroup(2).NewIssues("chromium").Server(server.URL).Replies()
+	issues, err := fetch.GetAll([]int{12, 3, 11, 5})
+	var notFound *NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("got error %v, want a NotFoundError", err)
+	}
+	if notFound.Project != "chromium" || !reflect.DeepEqual(notFound.IDs, []int{12, 11}) {
+		t.Errorf("got %v, want issues 12 and 11 missing from chromium", notFound.Error())
+	}
+	if len(issues) != 2 || issues[0].ID != 3 || issues[1].ID != 5 {
+		t.Fatalf("got %v issues, want 3 and 5", len(issues))
+	}
+	for _, issue := range issues {
+		if len(issue.Replies) != 1 {
+			t.Errorf("issue %v has %v replies, want 1", issue.ID, len(issue.Replies))
+		}
+	}
+
+	if _, err := fetch.Get(42); !errors.As(err, &notFound) || !reflect.DeepEqual(notFound.IDs, []int{42}) {
+		t.Errorf("Get(42) error = %v, want a NotFoundError", err)
+	}
+}
@@ -0,0 +1,290 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tbuckley/go-issuetracker/gcode"
+)
+
+const (
+	// maxCheckPasses bounds the re-fetches made by CheckTotal.
+	maxCheckPasses = 3
+	// maxPageRetries bounds the rounds of retries made by Partial.
+	maxPageRetries = 2
+	retryDelay     = time.Second
+)
+
+// TotalMismatchError is returned by CheckTotal queries whose results still
+// don't add up to the tracker's total after re-fetching.
+type TotalMismatchError struct {
+	URL      string
+	Expected int
+	Fetched  int
+}
+
+func (e *TotalMismatchError) Error() string {
+	return fmt.Sprintf("%v: fetched %v issues, expected %v", e.URL, e.Fetched, e.Expected)
+}
+
+type FailedPage struct {
+	Offset int
+	URL    string
+	Error  error
+}
+
+type FailedReplies struct {
+	IssueID int
+	Error   error
+}
+
+// IncompleteError is returned by Partial queries for the pages that still
+// failed after retrying, and for the issues whose replies couldn't be
+// fetched. The other pages were delivered as usual, and the issues were
+// delivered without replies.
+type IncompleteError struct {
+	Pages   []*FailedPage
+	Replies []*FailedReplies
+}
+
+func (e *IncompleteError) Error() string {
+	failures := make([]string, 0, len(e.Pages)+len(e.Replies))
+	for _, page := range e.Pages {
+		failures = append(failures, fmt.Sprintf("offset %v: %v", page.Offset, page.Error))
+	}
+	for _, replies := range e.Replies {
+		failures = append(failures, fmt.Sprintf("replies of %v: %v", replies.IssueID, replies.Error))
+	}
+	return fmt.Sprintf("%v pages and %v replies failed: %v", len(e.Pages), len(e.Replies), strings.Join(failures, "; "))
+}
+
+// InOrder makes FetchAllPages deliver pages in order of their offset rather
+// than as they complete.
+func (q *Query) InOrder() *Query {
+	clone := q.clone()
+	clone.inOrder = true
+	return clone
+}
+
+// Dedupe drops issues already delivered by an earlier page, which happens
+// when issues move between pages during a crawl.
+func (q *Query) Dedupe() *Query {
+	clone := q.clone()
+	clone.dedupe = true
+	return clone
+}
+
+// CheckTotal dedupes the results and compares their number to the tracker's
+// latest total, re-fetching short pages (and then every page) until they
+// match. If the total changed during the crawl, every page is fetched again
+// until a pass sees no change, and only that pass's issues are delivered.
+// Pages are therefore held back until the crawl is confirmed. If it can't be,
+// a TotalMismatchError with the number of issues delivered comes last.
+func (q *Query) CheckTotal() *Query {
+	clone := q.Dedupe()
+	clone.checkTotal = true
+	return clone
+}
+
+// Partial makes FetchAllPages carry on past failed pages and retry them once
+// every other page has been fetched. Pages that keep failing are reported in
+// a single IncompleteError, delivered last.
+func (q *Query) Partial() *Query {
+	clone := q.clone()
+	clone.partial = true
+	return clone
+}
+
+type pageResult struct {
+	offset int
+	feed   *gcode.IssuesFeed
+	err    error
+}
+
+// fetchPages fetches the pages at offsets concurrently and passes each to
+// deliver from the calling goroutine, in order of offsets if q.inOrder is set.
+// Retries is the number of earlier attempts at the pages.
+func (q *Query) fetchPages(offsets []int, retries int, deliver func(result *pageResult)) {
+	rest := q
+	if q.priority == Normal {
+		rest = q.Priority(Background)
+	}
+
+	type indexed struct {
+		index  int
+		result *pageResult
+	}
+	results := make(chan indexed)
+	for i, offset := range offsets {
+		go func(i int, offset int) {
+			page := rest.Offset(offset)
+			feed, err := q.workGroup.addQueryRetryTask(page, retries).Wait()
+			results <- indexed{i, &pageResult{offset: offset, feed: feed, err: err}}
+		}(i, offset)
+	}
+
+	pending := make(map[int]*pageResult)
+	next := 0
+	for range offsets {
+		r := <-results
+		if !q.inOrder {
+			deliver(r.result)
+			continue
+		}
+		pending[r.index] = r.result
+		for ; pending[next] != nil; next++ {
+			deliver(pending[next])
+			delete(pending, next)
+		}
+	}
+}
+
+func (q *Query) pageOffsets(total int) []int {
+	offsets := make([]int, 0)
+	for offset := q.offset + q.limit; offset < total; offset += q.limit {
+		offsets = append(offsets, offset)
+	}
+	return offsets
+}
+
+// fetchFirstPage fetches the page at the query's offset, retrying it if the
+// query is Partial.
+func (q *Query) fetchFirstPage() (*gcode.IssuesFeed, error) {
+	feed, err := q.FetchPage()
+	for retries := 1; err != nil && q.partial && retries <= maxPageRetries; retries++ {
+		time.Sleep(time.Duration(retries) * retryDelay)
+		feed, err = q.workGroup.addQueryRetryTask(q, retries).Wait()
+	}
+	return feed, err
+}
+
+func (q *Query) FetchAllPages() chan OptionalIssuesFeed {
+	feedChan := make(chan OptionalIssuesFeed)
+
+	go func() {
+		defer close(feedChan)
+
+		firstPage, err := q.fetchFirstPage()
+		if err != nil && q.partial {
+			feedChan <- OptionalIssuesFeed{Error: &IncompleteError{Pages: []*FailedPage{{Offset: q.offset, URL: q.URL(), Error: err}}}}
+			return
+		} else if err != nil {
+			feedChan <- OptionalIssuesFeed{Error: err}
+			return
+		}
+
+		seen := make(map[int]bool)
+		total := firstPage.TotalResults
+		short := make([]int, 0)
+		failed := make(map[int]error)
+		changed := false
+		// With CheckTotal the feeds are held back until the crawl is
+		// confirmed, so that issues dropped by a later pass aren't delivered
+		held := make([]*gcode.IssuesFeed, 0)
+		flush := func() {
+			for _, feed := range held {
+				feedChan <- OptionalIssuesFeed{IssuesFeed: feed}
+			}
+			held = make([]*gcode.IssuesFeed, 0)
+		}
+		deliver := func(result *pageResult) {
+			if result.err != nil && q.partial {
+				failed[result.offset] = result.err
+				return
+			} else if result.err != nil {
+				feedChan <- OptionalIssuesFeed{Error: result.err}
+				return
+			}
+			delete(failed, result.offset)
+			feed := result.feed
+			// Issues closed or filed mid-crawl change the total, so the
+			// latest page has the best idea of what to expect
+			if feed.TotalResults != total {
+				total = feed.TotalResults
+				changed = true
+			}
+			if len(feed.Issues) < q.limit && result.offset+q.limit < total {
+				short = append(short, result.offset)
+			}
+			if q.dedupe {
+				feed = dedupeFeed(feed, seen)
+			}
+			if q.checkTotal {
+				held = append(held, feed)
+				return
+			}
+			feedChan <- OptionalIssuesFeed{IssuesFeed: feed}
+		}
+
+		deliver(&pageResult{offset: q.offset, feed: firstPage})
+		q.fetchPages(q.pageOffsets(firstPage.TotalResults), 0, deliver)
+
+		// retryFailed retries the failed pages and reports those that keep
+		// failing
+		retryFailed := func() bool {
+			for retries := 1; len(failed) > 0 && retries <= maxPageRetries; retries++ {
+				time.Sleep(time.Duration(retries) * retryDelay)
+				q.fetchPages(failedOffsets(failed), retries, deliver)
+			}
+			if len(failed) == 0 {
+				return true
+			}
+			flush()
+			incomplete := &IncompleteError{Pages: make([]*FailedPage, 0, len(failed))}
+			for _, offset := range failedOffsets(failed) {
+				incomplete.Pages = append(incomplete.Pages, &FailedPage{Offset: offset, URL: q.Offset(offset).URL(), Error: failed[offset]})
+			}
+			feedChan <- OptionalIssuesFeed{Error: incomplete}
+			return false
+		}
+		if !retryFailed() || !q.checkTotal {
+			return
+		}
+		for check := 0; (changed || len(seen) != total-q.offset) && check < maxCheckPasses; check++ {
+			offsets := short
+			if changed || len(offsets) == 0 {
+				// If the results changed during the crawl, the issues seen
+				// may include closed ones and miss others that moved between
+				// pages, so only a full pass can be counted on
+				offsets = append([]int{q.offset}, q.pageOffsets(total)...)
+				seen = make(map[int]bool)
+				held = make([]*gcode.IssuesFeed, 0)
+			}
+			short = make([]int, 0)
+			changed = false
+			q.fetchPages(offsets, 0, deliver)
+		}
+		if !retryFailed() {
+			return
+		}
+		flush()
+		if changed || len(seen) != total-q.offset {
+			feedChan <- OptionalIssuesFeed{Error: &TotalMismatchError{URL: q.URL(), Expected: total - q.offset, Fetched: len(seen)}}
+		}
+	}()
+
+	return feedChan
+}
+
+func failedOffsets(failed map[int]error) []int {
+	offsets := make([]int, 0, len(failed))
+	for offset := range failed {
+		offsets = append(offsets, offset)
+	}
+	sort.Ints(offsets)
+	return offsets
+}
+
+func dedupeFeed(feed *gcode.IssuesFeed, seen map[int]bool) *gcode.IssuesFeed {
+	deduped := *feed
+	deduped.Issues = make([]*gcode.Issue, 0, len(feed.Issues))
+	for _, issue := range feed.Issues {
+		if !seen[issue.ID] {
+			seen[issue.ID] = true
+			deduped.Issues = append(deduped.Issues, issue)
+		}
+	}
+	return &deduped
+}
@@ -0,0 +1,262 @@
+package query
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tbuckley/go-issuetracker/gcode"
+)
+
+// fakeTracker serves the open issues feed of a project from memory. Before
+// answering a request, it calls change with the number of the request.
+type fakeTracker struct {
+	mu       sync.Mutex
+	open     []int
+	requests int
+	change   func(t *fakeTracker, request int)
+	// failing pages, by start index, get an error
+	failing map[int]bool
+	// failingReplies are the issues whose replies get an error
+	failingReplies map[int]bool
+}
+
+func newFakeTracker(numIssues int) *fakeTracker {
+	t := &fakeTracker{}
+	for id := 1; id <= numIssues; id++ {
+		t.open = append(t.open, id)
+	}
+	return t
+}
+
+func (t *fakeTracker) close(id int) {
+	for i, open := range t.open {
+		if open == id {
+			t.open = append(t.open[:i], t.open[i+1:]...)
+			return
+		}
+	}
+}
+
+func (t *fakeTracker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.requests++
+	if t.change != nil {
+		t.change(t, t.requests)
+	}
+
+	if strings.HasSuffix(r.URL.Path, "/comments/full") {
+		t.serveReplies(w, r)
+		return
+	}
+
+	start, _ := strconv.Atoi(r.URL.Query().Get("start-index"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("max-results"))
+	if t.failing[start] {
+		http.Error(w, "backend error", http.StatusInternalServerError)
+		return
+	}
+	matched := t.open
+	if q := r.URL.Query().Get("q"); strings.HasPrefix(q, "id:") {
+		ids := make(map[int]bool)
+		for _, id := range strings.Split(q[len("id:"):], ",") {
+			n, _ := strconv.Atoi(id)
+			ids[n] = true
+		}
+		matched = make([]int, 0)
+		for _, id := range t.open {
+			if ids[id] {
+				matched = append(matched, id)
+			}
+		}
+	}
+	feed := &gcode.IssuesFeed{}
+	feed.TotalResults = len(matched)
+	feed.StartIndex = start
+	feed.ItemsPerPage = limit
+	for i := start - 1; i >= 0 && i < len(matched) && i < start-1+limit; i++ {
+		issue := &gcode.Issue{ID: matched[i], State: "open"}
+		feed.Issues = append(feed.Issues, issue)
+	}
+	data, err := xml.Marshal(feed)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(data)
+}
+
+// serveReplies answers with a single reply for the issue.
+func (t *fakeTracker) serveReplies(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	id, _ := strconv.Atoi(parts[len(parts)-3])
+	if t.failingReplies[id] {
+		http.Error(w, "backend error", http.StatusInternalServerError)
+		return
+	}
+	feed := &gcode.RepliesFeed{Replies: []*gcode.Reply{{}}}
+	feed.TotalResults = 1
+	data, err := xml.Marshal(feed)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(data)
+}
+
+func TestCheckTotalIssueClosedMidCrawl(t *testing.T) {
+	tracker := newFakeTracker(60)
+	tracker.change = func(t *fakeTracker, request int) {
+		// Issue 40 is on the second page, which hasn't been fetched yet
+		if request == 2 {
+			t.close(40)
+		}
+	}
+	server := httptest.NewServer(tracker)
+	defer server.Close()
+
+	q := NewWorkGroup(4).NewQuery("chromium").Server(server.URL).InOrder().CheckTotal()
+	issues, err := CollectIssues(q.FetchAllIssues())
+	if err != nil {
+		t.Fatalf("FetchAllIssues: %v", err)
+	}
+	if len(issues) != 59 {
+		t.Errorf("got %v issues, want 59", len(issues))
+	}
+	for _, issue := range issues {
+		if issue.ID == 40 {
+			t.Errorf("got closed issue 40")
+		}
+	}
+	// One crawl, then one pass to confirm nothing was missed
+	if tracker.requests != 6 {
+		t.Errorf("made %v requests, want 6", tracker.requests)
+	}
+}
+
+func TestCheckTotalRefetchesMovedIssue(t *testing.T) {
+	tracker := newFakeTracker(60)
+	tracker.change = func(t *fakeTracker, request int) {
+		// Closing an issue on the first page, which was already fetched,
+		// moves issue 26 onto it, so it is missing from the second page
+		if request == 2 {
+			t.close(10)
+		}
+	}
+	server := httptest.NewServer(tracker)
+	defer server.Close()
+
+	q := NewWorkGroup(1).NewQuery("chromium").Server(server.URL).InOrder().CheckTotal()
+	issues, err := CollectIssues(q.FetchAllIssues())
+	if err != nil {
+		t.Fatalf("FetchAllIssues: %v", err)
+	}
+	found := make(map[int]bool)
+	for _, issue := range issues {
+		found[issue.ID] = true
+	}
+	if !found[26] {
+		t.Errorf("issue 26 is missing")
+	}
+	if found[10] {
+		t.Errorf("got closed issue 10")
+	}
+	if len(issues) != 59 {
+		t.Errorf("got %v issues, want 59", len(issues))
+	}
+}
+
+func TestCheckTotalDeliversFinalTotal(t *testing.T) {
+	tracker := newFakeTracker(60)
+	tracker.change = func(t *fakeTracker, request int) {
+		// Issues are closed and filed after their pages were delivered
+		switch request {
+		case 2:
+			t.close(5)
+		case 3:
+			t.close(30)
+			t.open = append(t.open, 61, 62)
+		}
+	}
+	server := httptest.NewServer(tracker)
+	defer server.Close()
+
+	q := NewWorkGroup(1).NewQuery("chromium").Server(server.URL).InOrder().CheckTotal()
+	issues, err := CollectIssues(q.FetchAllIssues())
+	if err != nil {
+		t.Fatalf("FetchAllIssues: %v", err)
+	}
+	if len(issues) != len(tracker.open) {
+		t.Errorf("got %v issues, want the final total of %v", len(issues), len(tracker.open))
+	}
+	for _, issue := range issues {
+		if issue.ID == 5 || issue.ID == 30 {
+			t.Errorf("got closed issue %v", issue.ID)
+		}
+	}
+}
+
+func TestPartialReportsPageFailingDuringCheck(t *testing.T) {
+	tracker := newFakeTracker(60)
+	tracker.change = func(t *fakeTracker, request int) {
+		// The crawl succeeds, but the pass confirming it loses a page
+		if request == 2 {
+			t.close(10)
+		}
+		if request == 4 {
+			t.failing = map[int]bool{26: true}
+		}
+	}
+	server := httptest.NewServer(tracker)
+	defer server.Close()
+
+	q := NewWorkGroup(1).NewQuery("chromium").Server(server.URL).InOrder().CheckTotal().Partial()
+	_, err := CollectIssues(q.FetchAllIssues())
+	var incomplete *IncompleteError
+	if !errors.As(err, &incomplete) {
+		t.Fatalf("got error %v, want an IncompleteError", err)
+	}
+	if len(incomplete.Pages) != 1 || incomplete.Pages[0].Offset != 25 {
+		t.Errorf("got failed pages %v, want offset 25", incomplete.Error())
+	}
+}
+
+func TestPartialKeepsIssuesWithFailedReplies(t *testing.T) {
+	tracker := newFakeTracker(30)
+	tracker.failingReplies = map[int]bool{7: true}
+	tracker.failing = map[int]bool{26: true}
+	server := httptest.NewServer(tracker)
+	defer server.Close()
+
+	q := NewWorkGroup(4).NewQuery("chromium").Server(server.URL).Partial()
+	issues, err := CollectIssues(q.FetchAllIssuesWithReplies())
+	var incomplete *IncompleteError
+	if !errors.As(err, &incomplete) {
+		t.Fatalf("got error %v, want an IncompleteError", err)
+	}
+	if len(incomplete.Pages) != 1 || incomplete.Pages[0].Offset != 25 {
+		t.Errorf("got failed pages %v, want offset 25", incomplete.Error())
+	}
+	if len(incomplete.Replies) != 1 || incomplete.Replies[0].IssueID != 7 {
+		t.Errorf("got failed replies %v, want issue 7", incomplete.Error())
+	}
+
+	if len(issues) != 25 {
+		t.Errorf("got %v issues, want 25", len(issues))
+	}
+	for _, issue := range issues {
+		want := 1
+		if issue.ID == 7 {
+			want = 0
+		}
+		if len(issue.Replies) != want {
+			t.Errorf("issue %v has %v replies, want %v", issue.ID, len(issue.Replies), want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package query
+
+import (
+	"sync"
+	"time"
+)
+
+// Priority orders the tasks waiting for a worker. Interactive tasks are
+// served before Normal ones, which are served before Background ones.
+type Priority int
+
+const (
+	Interactive Priority = iota
+	Normal
+	Background
+
+	numPriorities = 3
+)
+
+// DefaultStarvationTimeout is how long a task can wait before it starts
+// sharing the workers with higher priority tasks.
+const DefaultStarvationTimeout = 10 * time.Second
+
+// starvedShare is how many higher priority tasks are served for each starved
+// lower priority one, so a starved backlog never blocks new tasks.
+const starvedShare = 4
+
+var priorityNames = []string{"interactive", "normal", "background"}
+
+func (p Priority) String() string {
+	if p < 0 || int(p) >= len(priorityNames) {
+		return "unknown"
+	}
+	return priorityNames[p]
+}
+
+// taskQueue holds a FIFO queue of tasks per priority.
+type taskQueue struct {
+	lock       sync.Mutex
+	ready      *sync.Cond
+	queues     [numPriorities][]*task
+	starvation time.Duration
+	// skipped counts the tasks served while a lower priority task starved
+	skipped int
+}
+
+func newTaskQueue() *taskQueue {
+	q := &taskQueue{starvation: DefaultStarvationTimeout}
+	q.ready = sync.NewCond(&q.lock)
+	return q
+}
+
+func (q *taskQueue) push(t *task) {
+	priority := t.event.Priority
+	if priority < 0 || priority >= numPriorities {
+		priority = Normal
+	}
+	q.lock.Lock()
+	q.queues[priority] = append(q.queues[priority], t)
+	q.lock.Unlock()
+	q.ready.Signal()
+}
+
+// next picks the queue to serve: the highest priority one, except that every
+// starvedShare+1 tasks one goes to the lower priority queue whose oldest task
+// has been starved the longest.
+func (q *taskQueue) next(now time.Time) int {
+	top := -1
+	for p := range q.queues {
+		if len(q.queues[p]) > 0 {
+			top = p
+			break
+		}
+	}
+	if top < 0 {
+		return -1
+	}
+
+	starved := -1
+	for p := top + 1; p < numPriorities; p++ {
+		if len(q.queues[p]) == 0 {
+			continue
+		}
+		queued := q.queues[p][0].event.Queued
+		if now.Sub(queued) > q.starvation && (starved < 0 || queued.Before(q.queues[starved][0].event.Queued)) {
+			starved = p
+		}
+	}
+	if starved < 0 {
+		q.skipped = 0
+		return top
+	}
+	if q.skipped >= starvedShare {
+		q.skipped = 0
+		return starved
+	}
+	q.skipped++
+	return top
+}
+
+// pop blocks until a task is available.
+func (q *taskQueue) pop() *task {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for {
+		if p := q.next(time.Now()); p >= 0 {
+			t := q.queues[p][0]
+			q.queues[p][0] = nil
+			q.queues[p] = q.queues[p][1:]
+			return t
+		}
+		q.ready.Wait()
+	}
+}
+
+func (q *taskQueue) setStarvationTimeout(timeout time.Duration) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.starvation = timeout
+}
@@ -2,25 +2,34 @@ package query
 
 import (
 	"encoding/xml"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/tbuckley/go-issuetracker/gcode"
 )
 
+const DefaultServer = "https://code.google.com"
+
 type Query struct {
 	project string
 	client  *http.Client
+	server  string
 	query   []string
 	params  map[string]string
 
-	offset int
-	limit  int
+	offset   int
+	limit    int
+	priority Priority
+
+	inOrder    bool
+	dedupe     bool
+	checkTotal bool
+	partial    bool
 
 	workGroup *WorkGroup
 }
@@ -29,11 +38,13 @@ func newQuery(project string, workGroup *WorkGroup) *Query {
 	return &Query{
 		project: project,
 		client:  http.DefaultClient,
+		server:  DefaultServer,
 		query:   nil,
 		params:  map[string]string{"can": "open"},
 
-		offset: 0,
-		limit:  25,
+		offset:   0,
+		limit:    25,
+		priority: Normal,
 
 		workGroup: workGroup,
 	}
@@ -51,12 +62,20 @@ func (q *Query) clone() *Query {
 	}
 
 	return &Query{
-		project:   q.project,
-		client:    q.client,
-		query:     query,
-		params:    params,
-		offset:    q.offset,
-		limit:     q.limit,
+		project:  q.project,
+		client:   q.client,
+		server:   q.server,
+		query:    query,
+		params:   params,
+		offset:   q.offset,
+		limit:    q.limit,
+		priority: q.priority,
+
+		inOrder:    q.inOrder,
+		dedupe:     q.dedupe,
+		checkTotal: q.checkTotal,
+		partial:    q.partial,
+
 		workGroup: q.workGroup,
 	}
 }
@@ -67,6 +86,13 @@ func (q *Query) Client(client *http.Client) *Query {
 	return clone
 }
 
+// Server points the query at a different tracker, e.g. a local stand-in.
+func (q *Query) Server(server string) *Query {
+	clone := q.clone()
+	clone.server = server
+	return clone
+}
+
 func (q *Query) Can(can string) *Query {
 	clone := q.clone()
 	clone.params["can"] = can
@@ -123,6 +149,21 @@ func (q *Query) ClosedInRange(start time.Time, end time.Time) *Query {
 	return q.All().ClosedAfter(start).ClosedBefore(end)
 }
 
+// UpdatedAfter restricts the results to issues changed since date.
+func (q *Query) UpdatedAfter(date time.Time) *Query {
+	clone := q.clone()
+	clone.params["updated-min"] = date.UTC().Format(time.RFC3339)
+	return clone
+}
+
+// Priority sets the priority of the query's fetches. FetchAllPages runs the
+// pages after the first in the background unless the query is Interactive.
+func (q *Query) Priority(priority Priority) *Query {
+	clone := q.clone()
+	clone.priority = priority
+	return clone
+}
+
 func (q *Query) Offset(offset int) *Query {
 	clone := q.clone()
 	clone.offset = offset
@@ -135,7 +176,8 @@ func (q *Query) Limit(limit int) *Query {
 	return clone
 }
 
-func (q *Query) URL() string {
+// Values returns the feed parameters of the query.
+func (q *Query) Values() url.Values {
 	values := url.Values{}
 	for key, value := range q.params {
 		values.Set(key, value)
@@ -146,31 +188,50 @@ func (q *Query) URL() string {
 	if len(q.query) > 0 {
 		values.Set("q", strings.Join(q.query, " "))
 	}
+	return values
+}
+
+func (q *Query) URL() string {
+	return feedURL(q.server, "/feeds/issues/p/"+q.project+"/issues/full", q.Values())
+}
 
-	u := url.URL{
-		Scheme:   "https",
-		Host:     "code.google.com",
-		Path:     "/feeds/issues/p/" + q.project + "/issues/full",
-		RawQuery: values.Encode(),
+func feedURL(server string, path string, values url.Values) string {
+	u, err := url.Parse(server)
+	if err != nil || u.Host == "" {
+		u, _ = url.Parse(DefaultServer)
 	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	u.RawQuery = values.Encode()
 	return u.String()
 }
 
-func (q *Query) fetchPage() (*gcode.IssuesFeed, error) {
-	client := http.DefaultClient
-	if q.client != nil {
-		client = q.client
+func (q *Query) fetchPage(event *TaskEvent) (*gcode.IssuesFeed, error) {
+	if q.workGroup.source != nil {
+		return q.workGroup.source.FetchPage(q.project, q.Values())
 	}
 
+	client := q.workGroup.httpClient(q.client)
+
 	resp, err := client.Get(q.URL())
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
+	event.StatusCode = resp.StatusCode
 
 	data, err := ioutil.ReadAll(resp.Body)
+	event.Bytes = int64(len(data))
 	if err != nil {
 		return nil, err
 	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &ResponseError{
+			URL:        q.URL(),
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       string(data),
+		}
+	}
 
 	feed := new(gcode.IssuesFeed)
 	err = xml.Unmarshal(data, feed)
@@ -178,8 +239,7 @@ func (q *Query) fetchPage() (*gcode.IssuesFeed, error) {
 }
 
 func (q *Query) FetchPage() (*gcode.IssuesFeed, error) {
-	result := <-q.workGroup.addQueryTask(q)
-	return result.Feed, result.Error
+	return q.workGroup.addQueryTask(q).Wait()
 }
 
 type OptionalIssuesFeed struct {
@@ -197,39 +257,6 @@ type OptionalIssues struct {
 	Error  error
 }
 
-func (q *Query) FetchAllPages() chan OptionalIssuesFeed {
-	feedChan := make(chan OptionalIssuesFeed)
-
-	go func() {
-		firstPage, err := q.FetchPage()
-		if err != nil {
-			feedChan <- OptionalIssuesFeed{Error: err}
-			close(feedChan)
-		} else {
-			wg := new(sync.WaitGroup)
-			feedChan <- OptionalIssuesFeed{IssuesFeed: firstPage}
-			numPages := firstPage.NumPages()
-			for i := 1; i < numPages; i++ {
-				i := i
-				wg.Add(1)
-				go func() {
-					page, err := q.Offset(i * q.limit).FetchPage()
-					if err != nil {
-						feedChan <- OptionalIssuesFeed{Error: err}
-					} else {
-						feedChan <- OptionalIssuesFeed{IssuesFeed: page}
-					}
-					wg.Done()
-				}()
-			}
-			wg.Wait()
-			close(feedChan)
-		}
-	}()
-
-	return feedChan
-}
-
 func (q *Query) FetchAllIssues() chan OptionalIssue {
 	issueChan := make(chan OptionalIssue)
 
@@ -249,9 +276,23 @@ func (q *Query) FetchAllIssues() chan OptionalIssue {
 	return issueChan
 }
 
-// func GetIssues(issueChan chan OptionalIssue) ([]*gcode.Issue, error) {
-
-// }
+// CollectIssues reads every issue from issueChan, returning the errors
+// encountered joined together. The channel is always drained.
+func CollectIssues(issueChan chan OptionalIssue) ([]*gcode.Issue, error) {
+	issues := make([]*gcode.Issue, 0)
+	errs := make([]error, 0)
+	for optionalIssue := range issueChan {
+		if optionalIssue.Error != nil {
+			errs = append(errs, optionalIssue.Error)
+		} else {
+			issues = append(issues, optionalIssue.Issue)
+		}
+	}
+	if len(errs) == 1 {
+		return issues, errs[0]
+	}
+	return issues, errors.Join(errs...)
+}
 
 func BatchIssues(issueChan chan OptionalIssue, batchNum int) chan OptionalIssues {
 	issuesChan := make(chan OptionalIssues)
@@ -271,6 +312,7 @@ func BatchIssues(issueChan chan OptionalIssue, batchNum int) chan OptionalIssues
 		if len(issues) > 0 {
 			issuesChan <- OptionalIssues{Issues: issues}
 		}
+		close(issuesChan)
 	}()
 	return issuesChan
 }
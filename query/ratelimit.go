@@ -0,0 +1,261 @@
+package query
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// A throttled host never drops below this fraction of its configured rate
+	minRateFraction = 0.05
+	// Each successful response restores this fraction of the configured rate
+	recoveryFraction = 0.05
+)
+
+// Limit is a token bucket: Rate requests per second with bursts of up to
+// Burst requests. A Rate of zero or less means no limit.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+type bucket struct {
+	limit        Limit
+	rate         float64
+	tokens       float64
+	last         time.Time
+	blockedUntil time.Time
+	// slots holds a token per request in flight, if they are limited
+	slots chan struct{}
+}
+
+// reserve takes a token and returns how long to wait before using it.
+func (b *bucket) reserve(now time.Time) time.Duration {
+	var wait time.Duration
+	if b.limit.Rate > 0 {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+		b.tokens--
+		if b.tokens < 0 {
+			wait = time.Duration(-b.tokens / b.rate * float64(time.Second))
+		}
+	}
+	if blocked := b.blockedUntil.Sub(now); blocked > wait {
+		wait = blocked
+	}
+	return wait
+}
+
+// RateLimiter limits requests per host, both per second and, optionally, in
+// flight. It can be shared by any number of work groups and clients, and
+// slows down for a host when it answers with a quota error, recovering
+// gradually as requests succeed again.
+type RateLimiter struct {
+	lock            sync.Mutex
+	limit           Limit
+	hosts           map[string]Limit
+	concurrency     int
+	hostConcurrency map[string]int
+	buckets         map[string]*bucket
+}
+
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	return &RateLimiter{
+		limit:           Limit{Rate: rate, Burst: burst},
+		hosts:           make(map[string]Limit),
+		hostConcurrency: make(map[string]int),
+		buckets:         make(map[string]*bucket),
+	}
+}
+
+// SetHostLimit overrides the default limit for host, e.g. "code.google.com".
+func (l *RateLimiter) SetHostLimit(host string, rate float64, burst int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.hosts[host] = Limit{Rate: rate, Burst: burst}
+	delete(l.buckets, host)
+}
+
+// SetConcurrency limits the requests in flight to each host. Zero or less
+// means no limit.
+func (l *RateLimiter) SetConcurrency(concurrency int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.concurrency = concurrency
+	l.buckets = make(map[string]*bucket)
+}
+
+// SetHostConcurrency overrides the default concurrency for host.
+func (l *RateLimiter) SetHostConcurrency(host string, concurrency int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.hostConcurrency[host] = concurrency
+	delete(l.buckets, host)
+}
+
+func (l *RateLimiter) bucket(host string) *bucket {
+	b, ok := l.buckets[host]
+	if !ok {
+		limit, ok := l.hosts[host]
+		if !ok {
+			limit = l.limit
+		}
+		if limit.Burst < 1 {
+			limit.Burst = 1
+		}
+		b = &bucket{limit: limit, rate: limit.Rate, tokens: float64(limit.Burst), last: time.Now()}
+		concurrency, ok := l.hostConcurrency[host]
+		if !ok {
+			concurrency = l.concurrency
+		}
+		if concurrency > 0 {
+			b.slots = make(chan struct{}, concurrency)
+		}
+		l.buckets[host] = b
+	}
+	return b
+}
+
+// Wait blocks until a request to host is allowed.
+func (l *RateLimiter) Wait(host string) {
+	l.lock.Lock()
+	wait := l.bucket(host).reserve(time.Now())
+	l.lock.Unlock()
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+// acquire blocks until a request to host may be in flight and returns the
+// function that ends it.
+func (l *RateLimiter) acquire(host string) func() {
+	l.lock.Lock()
+	slots := l.bucket(host).slots
+	l.lock.Unlock()
+	if slots == nil {
+		return func() {}
+	}
+	slots <- struct{}{}
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-slots })
+	}
+}
+
+// Rate returns the current rate for host, which is below the configured one
+// while the host is throttling us.
+func (l *RateLimiter) Rate(host string) float64 {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.bucket(host).rate
+}
+
+// Throttled halves the rate for host. If retryAfter is positive, no requests
+// are made to the host until it has passed.
+func (l *RateLimiter) Throttled(host string, retryAfter time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	b := l.bucket(host)
+	b.rate = math.Max(b.rate/2, b.limit.Rate*minRateFraction)
+	if until := time.Now().Add(retryAfter); retryAfter > 0 && until.After(b.blockedUntil) {
+		b.blockedUntil = until
+	}
+}
+
+func (l *RateLimiter) succeeded(host string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	b := l.bucket(host)
+	b.rate = math.Min(b.limit.Rate, b.rate+b.limit.Rate*recoveryFraction)
+}
+
+// Transport wraps base so that every request waits for the limiter and quota
+// errors slow down later requests. A request is in flight until its response
+// body is closed.
+func (l *RateLimiter) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &limitedTransport{limiter: l, base: base}
+}
+
+// Client returns a copy of client whose requests go through the limiter.
+func (l *RateLimiter) Client(client *http.Client) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	limited := *client
+	limited.Transport = l.Transport(client.Transport)
+	return &limited
+}
+
+type limitedTransport struct {
+	limiter *RateLimiter
+	base    http.RoundTripper
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	release := t.limiter.acquire(host)
+	t.limiter.Wait(host)
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return resp, err
+	}
+	if isQuotaError(resp) {
+		t.limiter.Throttled(host, retryAfter(resp))
+	} else if resp.StatusCode < 400 {
+		t.limiter.succeeded(host)
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// isQuotaError recognizes rate limiting responses. A 403 only counts if its
+// body mentions a quota or rate limit, so the body is peeked and restored.
+// Other errors, such as a 503 during an outage, are left to the caller.
+func isQuotaError(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusForbidden:
+		peeked, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(peeked), resp.Body), resp.Body}
+		body := strings.ToLower(string(peeked))
+		return strings.Contains(body, "quota") || strings.Contains(body, "rate limit")
+	}
+	return false
+}
+
+func retryAfter(resp *http.Response) time.Duration {
+	value := resp.Header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
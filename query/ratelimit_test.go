@@ -0,0 +1,88 @@
+package query
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterConcurrency(t *testing.T) {
+	var lock sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		lock.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		lock.Lock()
+		inFlight--
+		lock.Unlock()
+	}))
+	defer server.Close()
+
+	limiter := NewRateLimiter(0, 0)
+	limiter.SetConcurrency(2)
+	client := limiter.Client(nil)
+
+	wg := new(sync.WaitGroup)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+	if maxInFlight != 2 {
+		t.Errorf("got %v requests in flight, want 2", maxInFlight)
+	}
+}
+
+func TestRateLimiterQuotaErrors(t *testing.T) {
+	status, body := 0, ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, body, status)
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	tests := []struct {
+		status    int
+		body      string
+		throttled bool
+	}{
+		{http.StatusTooManyRequests, "slow down", true},
+		{http.StatusForbidden, "Quota exceeded", true},
+		{http.StatusForbidden, "Permission denied", false},
+		{http.StatusServiceUnavailable, "backend error", false},
+	}
+	for _, test := range tests {
+		limiter := NewRateLimiter(10, 1)
+		status, body = test.status, test.body
+		resp, err := limiter.Client(nil).Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(data) != test.body+"\n" {
+			t.Errorf("%v %v: body changed to %q", test.status, test.body, data)
+		}
+		if throttled := limiter.Rate(u.Host) < 10; throttled != test.throttled {
+			t.Errorf("%v %v: throttled is %v, want %v", test.status, test.body, throttled, test.throttled)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package query
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/tbuckley/go-issuetracker/gcode"
+)
+
+// Replies fetches the comments on a single issue. Like Query, every method
+// returns a modified copy.
+type Replies struct {
+	project string
+	issueID int
+	client  *http.Client
+	server  string
+
+	offset int
+	limit  int
+
+	priority Priority
+
+	workGroup *WorkGroup
+}
+
+func newReplies(project string, issueID int, workGroup *WorkGroup) *Replies {
+	return &Replies{
+		project:   project,
+		issueID:   issueID,
+		client:    http.DefaultClient,
+		server:    DefaultServer,
+		limit:     100,
+		priority:  Normal,
+		workGroup: workGroup,
+	}
+}
+
+func (r *Replies) clone() *Replies {
+	clone := *r
+	return &clone
+}
+
+func (r *Replies) Client(client *http.Client) *Replies {
+	clone := r.clone()
+	clone.client = client
+	return clone
+}
+
+func (r *Replies) Priority(priority Priority) *Replies {
+	clone := r.clone()
+	clone.priority = priority
+	return clone
+}
+
+func (r *Replies) Server(server string) *Replies {
+	clone := r.clone()
+	clone.server = server
+	return clone
+}
+
+func (r *Replies) Offset(offset int) *Replies {
+	clone := r.clone()
+	clone.offset = offset
+	return clone
+}
+
+func (r *Replies) URL() string {
+	values := url.Values{}
+	values.Set("max-results", strconv.Itoa(r.limit))
+	values.Set("start-index", strconv.Itoa(r.offset+1))
+	path := "/feeds/issues/p/" + r.project + "/issues/" + strconv.Itoa(r.issueID) + "/comments/full"
+	return feedURL(r.server, path, values)
+}
+
+func (r *Replies) fetchPage(event *TaskEvent) (*gcode.RepliesFeed, error) {
+	client := r.workGroup.httpClient(r.client)
+
+	resp, err := client.Get(r.URL())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	event.StatusCode = resp.StatusCode
+
+	data, err := ioutil.ReadAll(resp.Body)
+	event.Bytes = int64(len(data))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &ResponseError{
+			URL:        r.URL(),
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       string(data),
+		}
+	}
+
+	feed := new(gcode.RepliesFeed)
+	err = xml.Unmarshal(data, feed)
+	return feed, err
+}
+
+// FetchAll fetches every page of replies through the work group, oldest first.
+func (r *Replies) FetchAll() ([]*gcode.Reply, error) {
+	replies := make([]*gcode.Reply, 0)
+	for page := r; ; {
+		feed, err := r.workGroup.addRepliesTask(page).Wait()
+		if err != nil {
+			return nil, err
+		}
+		replies = append(replies, feed.Replies...)
+		if len(feed.Replies) == 0 || len(replies) >= feed.TotalResults {
+			return replies, nil
+		}
+		page = page.Offset(len(replies))
+	}
+}
+
+// FetchAllIssuesWithReplies is FetchAllIssues with the replies of every issue
+// filled in. For Partial queries, issues whose replies can't be fetched are
+// delivered without them and reported in the IncompleteError.
+func (q *Query) FetchAllIssuesWithReplies() chan OptionalIssue {
+	if q.workGroup.source != nil {
+		return q.FetchAllIssues()
+	}
+
+	issueChan := make(chan OptionalIssue)
+
+	go func() {
+		wg := new(sync.WaitGroup)
+		lock := new(sync.Mutex)
+		incomplete := new(IncompleteError)
+		for optionalIssue := range q.FetchAllIssues() {
+			if err, ok := optionalIssue.Error.(*IncompleteError); ok && q.partial {
+				lock.Lock()
+				incomplete.Pages = append(incomplete.Pages, err.Pages...)
+				lock.Unlock()
+				continue
+			} else if optionalIssue.Error != nil {
+				issueChan <- optionalIssue
+				continue
+			}
+			wg.Add(1)
+			go func(issue *gcode.Issue) {
+				defer wg.Done()
+				replies, err := q.workGroup.NewReplies(q.project, issue.ID).Client(q.client).Server(q.server).Priority(q.priority).FetchAll()
+				if err != nil && q.partial {
+					lock.Lock()
+					incomplete.Replies = append(incomplete.Replies, &FailedReplies{IssueID: issue.ID, Error: err})
+					lock.Unlock()
+				} else if err != nil {
+					issueChan <- OptionalIssue{Error: err}
+					return
+				}
+				issue.Replies = replies
+				issueChan <- OptionalIssue{Issue: issue}
+			}(optionalIssue.Issue)
+		}
+		wg.Wait()
+		if len(incomplete.Pages) > 0 || len(incomplete.Replies) > 0 {
+			sort.Slice(incomplete.Replies, func(i, j int) bool {
+				return incomplete.Replies[i].IssueID < incomplete.Replies[j].IssueID
+			})
+			issueChan <- OptionalIssue{Error: incomplete}
+		}
+		close(issueChan)
+	}()
+
+	return issueChan
+}
@@ -0,0 +1,168 @@
+package query
+
+import (
+	"expvar"
+	"sync"
+	"time"
+)
+
+type TaskKind string
+
+const (
+	QueryTask   TaskKind = "query"
+	UpdateTask  TaskKind = "update"
+	RepliesTask TaskKind = "replies"
+)
+
+// TaskEvent describes one task run by a work group. Wait is the time the task
+// spent queued before a worker picked it up and Retries the number of earlier
+// attempts at the same work.
+type TaskEvent struct {
+	Kind       TaskKind
+	Priority   Priority
+	URL        string
+	Queued     time.Time
+	Started    time.Time
+	Wait       time.Duration
+	Duration   time.Duration
+	StatusCode int
+	Bytes      int64
+	Retries    int
+	Error      error
+}
+
+func newTaskEvent(kind TaskKind, url string) *TaskEvent {
+	return &TaskEvent{Kind: kind, URL: url}
+}
+
+// Observer is notified by a work group after each task finishes. Observers are
+// called from the workers, so they must be safe for concurrent use.
+type Observer interface {
+	ObserveTask(event *TaskEvent)
+}
+
+type ObserverFunc func(event *TaskEvent)
+
+func (f ObserverFunc) ObserveTask(event *TaskEvent) {
+	f(event)
+}
+
+type KindStats struct {
+	Tasks       int           `json:"tasks"`
+	Failed      int           `json:"failed"`
+	Retries     int           `json:"retries"`
+	Bytes       int64         `json:"bytes"`
+	Duration    time.Duration `json:"duration"`
+	MaxDuration time.Duration `json:"maxDuration"`
+	Wait        time.Duration `json:"wait"`
+	MaxWait     time.Duration `json:"maxWait"`
+}
+
+// Stats is a snapshot of a work group's activity. Durations are totals over
+// every finished task of a kind.
+type Stats struct {
+	Workers     int                     `json:"workers"`
+	Queued      int                     `json:"queued"`
+	Running     int                     `json:"running"`
+	Kinds       map[TaskKind]*KindStats `json:"kinds"`
+	StatusCodes map[int]int             `json:"statusCodes"`
+}
+
+type statsCollector struct {
+	lock      sync.Mutex
+	stats     Stats
+	observers []Observer
+}
+
+func newStatsCollector(workers int) *statsCollector {
+	return &statsCollector{stats: Stats{
+		Workers:     workers,
+		Kinds:       make(map[TaskKind]*KindStats),
+		StatusCodes: make(map[int]int),
+	}}
+}
+
+func (c *statsCollector) queued(event *TaskEvent) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	event.Queued = time.Now()
+	c.stats.Queued++
+}
+
+func (c *statsCollector) started(event *TaskEvent) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	event.Started = time.Now()
+	event.Wait = event.Started.Sub(event.Queued)
+	c.stats.Queued--
+	c.stats.Running++
+}
+
+func (c *statsCollector) finished(event *TaskEvent, err error) {
+	event.Duration = time.Since(event.Started)
+	event.Error = err
+
+	c.lock.Lock()
+	c.stats.Running--
+	kind, ok := c.stats.Kinds[event.Kind]
+	if !ok {
+		kind = new(KindStats)
+		c.stats.Kinds[event.Kind] = kind
+	}
+	kind.Tasks++
+	if err != nil {
+		kind.Failed++
+	}
+	if event.Retries > 0 {
+		kind.Retries++
+	}
+	kind.Bytes += event.Bytes
+	kind.Duration += event.Duration
+	kind.Wait += event.Wait
+	if event.Duration > kind.MaxDuration {
+		kind.MaxDuration = event.Duration
+	}
+	if event.Wait > kind.MaxWait {
+		kind.MaxWait = event.Wait
+	}
+	if event.StatusCode != 0 {
+		c.stats.StatusCodes[event.StatusCode]++
+	}
+	observers := c.observers
+	c.lock.Unlock()
+
+	for _, observer := range observers {
+		observer.ObserveTask(event)
+	}
+}
+
+func (g *WorkGroup) AddObserver(observer Observer) {
+	g.stats.lock.Lock()
+	defer g.stats.lock.Unlock()
+	g.stats.observers = append(g.stats.observers, observer)
+}
+
+// Stats returns a snapshot of the work group's activity so far.
+func (g *WorkGroup) Stats() Stats {
+	g.stats.lock.Lock()
+	defer g.stats.lock.Unlock()
+	stats := g.stats.stats
+	stats.Kinds = make(map[TaskKind]*KindStats, len(g.stats.stats.Kinds))
+	for kind, kindStats := range g.stats.stats.Kinds {
+		copied := *kindStats
+		stats.Kinds[kind] = &copied
+	}
+	stats.StatusCodes = make(map[int]int, len(g.stats.stats.StatusCodes))
+	for code, count := range g.stats.stats.StatusCodes {
+		stats.StatusCodes[code] = count
+	}
+	return stats
+}
+
+// PublishExpvar exposes the work group's stats on /debug/vars under name.
+// Like expvar.Publish, it panics if the name is already in use.
+func (g *WorkGroup) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return g.Stats()
+	}))
+}
@@ -0,0 +1,207 @@
+package query
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/tbuckley/go-issuetracker/gcode"
+)
+
+var (
+	EmptyUpdate = errors.New("Update has no changes")
+)
+
+type ResponseError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%v: %v", e.URL, e.Status)
+}
+
+// Update collects changes to a single issue. Like Query, every method returns
+// a modified copy.
+type Update struct {
+	project string
+	issueID int
+	client  *http.Client
+	server  string
+
+	summary string
+	status  string
+	owner   string
+	labels  []string
+	ccs     []string
+	comment string
+
+	priority Priority
+
+	workGroup *WorkGroup
+}
+
+func newUpdate(project string, issueID int, workGroup *WorkGroup) *Update {
+	return &Update{
+		project:   project,
+		issueID:   issueID,
+		client:    http.DefaultClient,
+		server:    DefaultServer,
+		priority:  Normal,
+		workGroup: workGroup,
+	}
+}
+
+func (u *Update) clone() *Update {
+	clone := *u
+	clone.labels = append([]string(nil), u.labels...)
+	clone.ccs = append([]string(nil), u.ccs...)
+	return &clone
+}
+
+func (u *Update) IssueID() int {
+	return u.issueID
+}
+
+func (u *Update) Client(client *http.Client) *Update {
+	clone := u.clone()
+	clone.client = client
+	return clone
+}
+
+func (u *Update) Priority(priority Priority) *Update {
+	clone := u.clone()
+	clone.priority = priority
+	return clone
+}
+
+func (u *Update) Server(server string) *Update {
+	clone := u.clone()
+	clone.server = server
+	return clone
+}
+
+func (u *Update) AddLabel(label string) *Update {
+	clone := u.clone()
+	clone.labels = append(clone.labels, label)
+	return clone
+}
+
+func (u *Update) RemoveLabel(label string) *Update {
+	clone := u.clone()
+	clone.labels = append(clone.labels, "-"+label)
+	return clone
+}
+
+func (u *Update) SetStatus(status string) *Update {
+	clone := u.clone()
+	clone.status = status
+	return clone
+}
+
+func (u *Update) SetOwner(owner string) *Update {
+	clone := u.clone()
+	clone.owner = owner
+	return clone
+}
+
+func (u *Update) SetSummary(summary string) *Update {
+	clone := u.clone()
+	clone.summary = summary
+	return clone
+}
+
+func (u *Update) AddCC(cc string) *Update {
+	clone := u.clone()
+	clone.ccs = append(clone.ccs, cc)
+	return clone
+}
+
+func (u *Update) RemoveCC(cc string) *Update {
+	clone := u.clone()
+	clone.ccs = append(clone.ccs, "-"+cc)
+	return clone
+}
+
+func (u *Update) Comment(comment string) *Update {
+	clone := u.clone()
+	clone.comment = comment
+	return clone
+}
+
+func (u *Update) Empty() bool {
+	return u.comment == "" && u.summary == "" && u.status == "" && u.owner == "" &&
+		len(u.labels) == 0 && len(u.ccs) == 0
+}
+
+func (u *Update) Entry() *gcode.UpdateEntry {
+	entry := &gcode.UpdateEntry{Content: u.comment}
+	if u.summary != "" || u.status != "" || u.owner != "" || len(u.labels) > 0 || len(u.ccs) > 0 {
+		entry.Updates = &gcode.Updates{
+			Summary:      u.summary,
+			Status:       u.status,
+			OwnerUpdate:  u.owner,
+			LabelChanges: u.labels,
+			CCChanges:    u.ccs,
+		}
+	}
+	return entry
+}
+
+func (u *Update) URL() string {
+	path := "/feeds/issues/p/" + u.project + "/issues/" + strconv.Itoa(u.issueID) + "/comments/full"
+	return feedURL(u.server, path, url.Values{})
+}
+
+func (u *Update) post(event *TaskEvent) (*gcode.Reply, error) {
+	if u.Empty() {
+		return nil, EmptyUpdate
+	}
+	if u.workGroup.source != nil {
+		return nil, OfflineSource
+	}
+
+	body, err := xml.Marshal(u.Entry())
+	if err != nil {
+		return nil, err
+	}
+
+	client := u.workGroup.httpClient(u.client)
+	resp, err := client.Post(u.URL(), "application/atom+xml", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	event.StatusCode = resp.StatusCode
+
+	data, err := ioutil.ReadAll(resp.Body)
+	event.Bytes = int64(len(data))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return nil, &ResponseError{
+			URL:        u.URL(),
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       string(data),
+		}
+	}
+
+	reply := new(gcode.Reply)
+	err = xml.Unmarshal(data, reply)
+	return reply, err
+}
+
+// Post sends the update through the work group and returns the reply the
+// tracker created for it.
+func (u *Update) Post() (*gcode.Reply, error) {
+	return u.workGroup.addUpdateTask(u).Wait()
+}
@@ -0,0 +1,139 @@
+package query
+
+import (
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/tbuckley/go-issuetracker/gcode"
+)
+
+// postedUpdate is what a stand-in tracker received for an update.
+type postedUpdate struct {
+	Method      string
+	Path        string
+	ContentType string
+	Entry       *gcode.UpdateEntry
+}
+
+func newUpdateServer(t *testing.T, status int, response string, posted *postedUpdate) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted.Method = r.Method
+		posted.Path = r.URL.Path
+		posted.ContentType = r.Header.Get("Content-Type")
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("reading body: %v", err)
+		}
+		posted.Entry = new(gcode.UpdateEntry)
+		if err := xml.Unmarshal(data, posted.Entry); err != nil {
+			t.Errorf("posted entry %s: %v", data, err)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(response))
+	}))
+}
+
+func TestUpdatePost(t *testing.T) {
+	posted := new(postedUpdate)
+	server := newUpdateServer(t, http.StatusCreated, `<entry xmlns="http://www.w3.org/2005/Atom"><content>Triaged</content><author><name>bot</name></author></entry>`, posted)
+	defer server.Close()
+
+	update := NewWorkGroup(1).NewUpdate("chromium", 123).Server(server.URL).
+		Comment("Triaged").
+		SetStatus("Available").
+		AddLabel("Pri-1").
+		RemoveLabel("Pri-2").
+		AddCC("someone@chromium.org").
+		RemoveCC("other@chromium.org")
+	reply, err := update.Post()
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	if reply.Content != "Triaged" || reply.Author != "bot" {
+		t.Errorf("got reply %+v", reply)
+	}
+
+	if posted.Method != "POST" || posted.Path != "/feeds/issues/p/chromium/issues/123/comments/full" {
+		t.Errorf("got %v %v", posted.Method, posted.Path)
+	}
+	if posted.ContentType != "application/atom+xml" {
+		t.Errorf("got content type %v", posted.ContentType)
+	}
+	if posted.Entry.Content != "Triaged" {
+		t.Errorf("got content %q", posted.Entry.Content)
+	}
+	want := &gcode.Updates{
+		Status:       "Available",
+		LabelChanges: []string{"Pri-1", "-Pri-2"},
+		CCChanges:    []string{"someone@chromium.org", "-other@chromium.org"},
+	}
+	if !reflect.DeepEqual(posted.Entry.Updates, want) {
+		t.Errorf("got updates %+v, want %+v", posted.Entry.Updates, want)
+	}
+}
+
+func TestUpdatePostCommentOnly(t *testing.T) {
+	posted := new(postedUpdate)
+	server := newUpdateServer(t, http.StatusCreated, `<entry/>`, posted)
+	defer server.Close()
+
+	_, err := NewWorkGroup(1).NewUpdate("chromium", 123).Server(server.URL).Comment("Ping").Post()
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	if posted.Entry.Updates != nil {
+		t.Errorf("got updates %+v for a comment", posted.Entry.Updates)
+	}
+}
+
+func TestUpdatePostError(t *testing.T) {
+	posted := new(postedUpdate)
+	server := newUpdateServer(t, http.StatusForbidden, "Permission denied", posted)
+	defer server.Close()
+
+	_, err := NewWorkGroup(1).NewUpdate("chromium", 123).Server(server.URL).SetOwner("someone@chromium.org").Post()
+	var responseErr *ResponseError
+	if !errors.As(err, &responseErr) {
+		t.Fatalf("got error %v, want a ResponseError", err)
+	}
+	if responseErr.StatusCode != http.StatusForbidden || responseErr.Body != "Permission denied" {
+		t.Errorf("got %+v", responseErr)
+	}
+}
+
+func TestUpdatePostEmpty(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	_, err := NewWorkGroup(1).NewUpdate("chromium", 123).Server(server.URL).Post()
+	if err != EmptyUpdate {
+		t.Errorf("got error %v, want EmptyUpdate", err)
+	}
+	if requests != 0 {
+		t.Errorf("made %v requests for an empty update", requests)
+	}
+}
+
+func TestUpdatePostOffline(t *testing.T) {
+	wg := NewWorkGroup(1)
+	wg.SetSource(offlineStub{})
+	_, err := wg.NewUpdate("chromium", 123).Comment("Ping").Post()
+	if err != OfflineSource {
+		t.Errorf("got error %v, want OfflineSource", err)
+	}
+}
+
+type offlineStub struct{}
+
+func (offlineStub) FetchPage(project string, values url.Values) (*gcode.IssuesFeed, error) {
+	return new(gcode.IssuesFeed), nil
+}
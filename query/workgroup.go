@@ -2,111 +2,222 @@ package query
 
 import (
 	"errors"
+	"fmt"
 	"log"
-	"sync"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"time"
 
 	"github.com/tbuckley/go-issuetracker/gcode"
 )
 
 var (
-	UnknownTask = errors.New("Cannot handle task")
+	UnknownTask   = errors.New("Cannot handle task")
+	OfflineSource = errors.New("Cannot post updates to an offline source")
 )
 
-type task interface {
-	SetError(err error)
+// Source answers queries in place of the tracker, e.g. from a dump on disk.
+// Values are the feed parameters of the query (see Query.Values).
+type Source interface {
+	FetchPage(project string, values url.Values) (*gcode.IssuesFeed, error)
 }
 
-type queryResult struct {
-	Feed  *gcode.IssuesFeed
-	Error error
+// Request is a typed unit of work that can be submitted to a work group.
+type Request[T any] interface {
+	Kind() TaskKind
+	URL() string
+	Do(event *TaskEvent) (T, error)
 }
 
-type queryTask struct {
-	Query      *Query
-	ResultChan chan *queryResult
+// Future is the pending result of a submitted task.
+type Future[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
 }
 
-func (t *queryTask) SetResponse(feed *gcode.IssuesFeed) {
-	t.ResultChan <- &queryResult{Feed: feed}
+// Done is closed once the result is available.
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
 }
-func (t *queryTask) SetError(err error) {
-	t.ResultChan <- &queryResult{Error: err}
+
+// Wait blocks until the task has run and returns its result.
+func (f *Future[T]) Wait() (T, error) {
+	<-f.done
+	return f.value, f.err
+}
+
+type task struct {
+	url    string
+	event  *TaskEvent
+	run    func(event *TaskEvent) error
+	finish func()
 }
 
 type WorkGroup struct {
-	taskChan chan task
+	queue   *taskQueue
+	source  Source
+	limiter *RateLimiter
+	stats   *statsCollector
 }
 
 func NewWorkGroup(numWorkers int) *WorkGroup {
-	taskChan := make(chan task)
-
+	g := &WorkGroup{
+		queue: newTaskQueue(),
+		stats: newStatsCollector(numWorkers),
+	}
 	for i := 0; i < numWorkers; i++ {
-		go func(num int) {
-			for {
-				task, ok := <-taskChan
-				if !ok {
-					return
-				}
+		go g.work(i)
+	}
+	return g
+}
 
-				switch actualTask := task.(type) {
-				case *queryTask:
-					log.Printf("[%v] Fetching query: %v", num, actualTask.Query.URL())
-					feed, err := actualTask.Query.fetchPage()
-					if err != nil {
-						actualTask.SetError(err)
-					} else {
-						actualTask.SetResponse(feed)
-					}
-				default:
-					log.Printf("[%v] Cannot handle task: %#v", num, actualTask)
-					task.SetError(UnknownTask)
-				}
+func (g *WorkGroup) work(num int) {
+	for {
+		t := g.queue.pop()
+		log.Printf("[%v] Running %v %v task: %v", num, t.event.Priority, t.event.Kind, t.url)
+		g.stats.started(t.event)
+		err := t.run(t.event)
+		g.stats.finished(t.event, err)
+		t.finish()
+	}
+}
 
+// Submit runs fn on one of the work group's workers at Normal priority. The
+// kind and URL are only used for logging and stats.
+func Submit[T any](g *WorkGroup, kind TaskKind, url string, fn func(event *TaskEvent) (T, error)) *Future[T] {
+	return SubmitPriority(g, Normal, kind, url, fn)
+}
+
+func SubmitPriority[T any](g *WorkGroup, priority Priority, kind TaskKind, url string, fn func(event *TaskEvent) (T, error)) *Future[T] {
+	future := &Future[T]{done: make(chan struct{})}
+	event := newTaskEvent(kind, url)
+	event.Priority = priority
+	t := &task{
+		url:   url,
+		event: event,
+		run: func(event *TaskEvent) (err error) {
+			// A panicking task fails its future instead of killing the worker
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("Task %v panicked: %v\n%s", url, r, debug.Stack())
+					err = fmt.Errorf("Task panicked: %v", r)
+					future.err = err
+				}
+			}()
+			if fn == nil {
+				future.err = UnknownTask
+				return future.err
 			}
-		}(i)
+			future.value, future.err = fn(event)
+			return future.err
+		},
+		finish: func() {
+			close(future.done)
+		},
 	}
+	g.stats.queued(t.event)
+	g.queue.push(t)
+	return future
+}
+
+// PriorityRequest is a Request that isn't run at Normal priority.
+type PriorityRequest interface {
+	Priority() Priority
+}
+
+func SubmitRequest[T any](g *WorkGroup, request Request[T]) *Future[T] {
+	priority := Normal
+	if p, ok := request.(PriorityRequest); ok {
+		priority = p.Priority()
+	}
+	return SubmitPriority(g, priority, request.Kind(), request.URL(), request.Do)
+}
+
+// SubmitFunc runs fn on one of the work group's workers.
+func (g *WorkGroup) SubmitFunc(priority Priority, kind TaskKind, url string, fn func() error) *Future[struct{}] {
+	return SubmitPriority(g, priority, kind, url, func(event *TaskEvent) (struct{}, error) {
+		return struct{}{}, fn()
+	})
+}
 
-	return &WorkGroup{taskChan}
+// SetStarvationTimeout sets how long a task can wait before it is served
+// ahead of higher priority tasks.
+func (g *WorkGroup) SetStarvationTimeout(timeout time.Duration) {
+	g.queue.setStarvationTimeout(timeout)
+}
+
+// SetSource makes every query of the work group read from source instead of
+// the tracker. Issues from a source are expected to carry their replies.
+func (g *WorkGroup) SetSource(source Source) {
+	g.source = source
+}
+
+// SetRateLimiter makes every request of the work group wait for limiter,
+// which may be shared with other work groups.
+func (g *WorkGroup) SetRateLimiter(limiter *RateLimiter) {
+	g.limiter = limiter
+}
+
+func (g *WorkGroup) httpClient(client *http.Client) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if g.limiter == nil {
+		return client
+	}
+	return g.limiter.Client(client)
 }
 
 func (g *WorkGroup) NewQuery(project string) *Query {
 	return newQuery(project, g)
 }
 
-func (g *WorkGroup) addQueryTaskWithOutput(query *Query, resultChan chan *queryResult) {
-	go func() {
-		g.taskChan <- &queryTask{
-			Query:      query,
-			ResultChan: resultChan,
-		}
-	}()
+func (g *WorkGroup) NewUpdate(project string, issueID int) *Update {
+	return newUpdate(project, issueID, g)
 }
 
-func (g *WorkGroup) addQueryTask(query *Query) chan *queryResult {
-	resultChan := make(chan *queryResult)
-	g.addQueryTaskWithOutput(query, resultChan)
-	return resultChan
+func (g *WorkGroup) NewReplies(project string, issueID int) *Replies {
+	return newReplies(project, issueID, g)
 }
 
-func (g *WorkGroup) addQueryTasks(queries []*Query) chan []*queryResult {
-	multiResultChan := make(chan []*queryResult)
+func (g *WorkGroup) addQueryTask(query *Query) *Future[*gcode.IssuesFeed] {
+	return g.addQueryRetryTask(query, 0)
+}
 
-	go func() {
-		wg := new(sync.WaitGroup)
+func (g *WorkGroup) addQueryRetryTask(query *Query, retries int) *Future[*gcode.IssuesFeed] {
+	return SubmitPriority(g, query.priority, QueryTask, query.URL(), func(event *TaskEvent) (*gcode.IssuesFeed, error) {
+		event.Retries = retries
+		return query.fetchPage(event)
+	})
+}
 
-		results := make([]*queryResult, len(queries))
+func (g *WorkGroup) addQueryTasks(queries []*Query) chan []OptionalIssuesFeed {
+	multiResultChan := make(chan []OptionalIssuesFeed)
+
+	go func() {
+		futures := make([]*Future[*gcode.IssuesFeed], len(queries))
 		for i, query := range queries {
-			wg.Add(1)
-			go func(i int, query *Query) {
-				results[i] = <-g.addQueryTask(query)
-				wg.Done()
-			}(i, query)
+			futures[i] = g.addQueryTask(query)
 		}
 
-		wg.Wait()
+		results := make([]OptionalIssuesFeed, len(queries))
+		for i, future := range futures {
+			feed, err := future.Wait()
+			results[i] = OptionalIssuesFeed{IssuesFeed: feed, Error: err}
+		}
 
 		multiResultChan <- results
 	}()
 
 	return multiResultChan
 }
+
+func (g *WorkGroup) addUpdateTask(update *Update) *Future[*gcode.Reply] {
+	return SubmitPriority(g, update.priority, UpdateTask, update.URL(), update.post)
+}
+
+func (g *WorkGroup) addRepliesTask(replies *Replies) *Future[*gcode.RepliesFeed] {
+	return SubmitPriority(g, replies.priority, RepliesTask, replies.URL(), replies.fetchPage)
+}
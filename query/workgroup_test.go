@@ -0,0 +1,84 @@
+package query
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPanickingTaskFailsFuture(t *testing.T) {
+	g := NewWorkGroup(1)
+
+	_, err := Submit(g, QueryTask, "panic", func(event *TaskEvent) (int, error) {
+		panic("boom")
+	}).Wait()
+	if err == nil {
+		t.Fatalf("panicking task returned no error")
+	}
+
+	// The only worker must still be running
+	value, err := Submit(g, QueryTask, "after", func(event *TaskEvent) (int, error) {
+		return 42, nil
+	}).Wait()
+	if err != nil || value != 42 {
+		t.Errorf("task after panic = %v, %v; want 42, nil", value, err)
+	}
+}
+
+func TestNilTaskIsUnknown(t *testing.T) {
+	g := NewWorkGroup(1)
+	_, err := Submit[int](g, QueryTask, "nil", nil).Wait()
+	if !errors.Is(err, UnknownTask) {
+		t.Errorf("nil task error = %v, want UnknownTask", err)
+	}
+}
+
+func queuedTask(priority Priority, queued time.Time) *task {
+	event := newTaskEvent(QueryTask, priority.String())
+	event.Priority = priority
+	event.Queued = queued
+	return &task{url: priority.String(), event: event}
+}
+
+func TestStarvedBacklogDoesNotBlockInteractive(t *testing.T) {
+	q := newTaskQueue()
+	q.setStarvationTimeout(10 * time.Millisecond)
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		q.push(queuedTask(Background, now.Add(-time.Second)))
+	}
+	q.push(queuedTask(Interactive, now))
+
+	if got := q.pop().event.Priority; got != Interactive {
+		t.Errorf("first task is %v, want interactive", got)
+	}
+}
+
+func TestStarvedTasksShareWorkers(t *testing.T) {
+	q := newTaskQueue()
+	q.setStarvationTimeout(10 * time.Millisecond)
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		q.push(queuedTask(Background, now.Add(-time.Second)))
+	}
+	for i := 0; i < 2*(starvedShare+1); i++ {
+		q.push(queuedTask(Interactive, now))
+	}
+
+	order := make([]Priority, 0)
+	for i := 0; i < 2*(starvedShare+1); i++ {
+		order = append(order, q.pop().event.Priority)
+	}
+	background := 0
+	for i, priority := range order {
+		if priority == Background {
+			background++
+			if i%(starvedShare+1) != starvedShare {
+				t.Errorf("background task served at %v, want every %vth: %v", i, starvedShare+1, order)
+			}
+		}
+	}
+	if background != 2 {
+		t.Errorf("served %v background tasks, want 2: %v", background, order)
+	}
+}
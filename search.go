@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/tbuckley/go-issuetracker/query"
+	"github.com/tbuckley/go-issuetracker/search"
+)
+
+func runSearch(wg *query.WorkGroup, client *http.Client, args []string) {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	project := flags.String("project", "chromium", "Project to search")
+	q := flags.String("query", "", "Search query selecting the issues to index")
+	label := flags.String("label", *fLabel, "Label selecting the issues to index")
+	all := flags.Bool("all", false, "Index closed issues too")
+	limit := flags.Int("limit", 20, "Maximum number of results")
+	flags.Parse(args)
+
+	text := strings.Join(flags.Args(), " ")
+	if text == "" {
+		fmt.Println("Usage: ./go-issuetracker search [flags] WORDS or \"PHRASE\"")
+		return
+	}
+
+	fetch := wg.NewQuery(*project).Client(client)
+	if *q != "" {
+		fetch = fetch.Query(*q)
+	}
+	if *label != "" {
+		fetch = fetch.Label(*label)
+	}
+	if *all {
+		fetch = fetch.All()
+	}
+	issues, err := query.CollectIssues(fetch.FetchAllIssuesWithReplies())
+	if err != nil {
+		fmt.Printf("Error: %v\n", err.Error())
+		return
+	}
+
+	index := search.Build(issues)
+	results := index.Search(text, *limit)
+	fmt.Printf("%v of %v issues match\n", len(results), index.Len())
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for _, result := range results {
+		fmt.Fprintf(tw, "%.2f\tcrbug.com/%v\t%v\t%v\n", result.Score, result.Issue.ID, result.Issue.State, result.Issue.Title)
+	}
+	tw.Flush()
+}
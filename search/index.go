@@ -0,0 +1,213 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/tbuckley/go-issuetracker/gcode"
+)
+
+const (
+	k1 = 1.2
+	b  = 0.75
+
+	// fieldGap separates the positions of consecutive fields so that phrases
+	// never match across them.
+	fieldGap = 100
+)
+
+// Tokenize case-folds text and splits it into words of letters and digits.
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+type document struct {
+	issue  *gcode.Issue
+	length int
+	terms  []string
+}
+
+// Index is an in-memory inverted index over issue titles, content and
+// replies, with positions for phrase queries.
+type Index struct {
+	docs        map[int]*document
+	postings    map[string]map[int][]int
+	totalLength int
+}
+
+func NewIndex() *Index {
+	return &Index{
+		docs:     make(map[int]*document),
+		postings: make(map[string]map[int][]int),
+	}
+}
+
+func Build(issues []*gcode.Issue) *Index {
+	index := NewIndex()
+	for _, issue := range issues {
+		index.Add(issue)
+	}
+	return index
+}
+
+func fields(issue *gcode.Issue) []string {
+	texts := []string{issue.Title, issue.Content}
+	for _, reply := range issue.Replies {
+		texts = append(texts, reply.Content)
+	}
+	return texts
+}
+
+// Add indexes issue, replacing any previous version with the same ID.
+func (idx *Index) Add(issue *gcode.Issue) {
+	idx.Remove(issue.ID)
+
+	doc := &document{issue: issue}
+	position := 0
+	for _, text := range fields(issue) {
+		for _, token := range Tokenize(text) {
+			positions, ok := idx.postings[token]
+			if !ok {
+				positions = make(map[int][]int)
+				idx.postings[token] = positions
+			}
+			if len(positions[issue.ID]) == 0 {
+				doc.terms = append(doc.terms, token)
+			}
+			positions[issue.ID] = append(positions[issue.ID], position)
+			position++
+			doc.length++
+		}
+		position += fieldGap
+	}
+	idx.docs[issue.ID] = doc
+	idx.totalLength += doc.length
+}
+
+func (idx *Index) Remove(id int) {
+	doc, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	for _, term := range doc.terms {
+		delete(idx.postings[term], id)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	idx.totalLength -= doc.length
+	delete(idx.docs, id)
+}
+
+func (idx *Index) Len() int {
+	return len(idx.docs)
+}
+
+func (idx *Index) idf(term string) float64 {
+	n := float64(len(idx.postings[term]))
+	total := float64(len(idx.docs))
+	return math.Log(1 + (total-n+0.5)/(n+0.5))
+}
+
+func (idx *Index) bm25(term string, id int) float64 {
+	freq := float64(len(idx.postings[term][id]))
+	if freq == 0 {
+		return 0
+	}
+	avgLength := float64(idx.totalLength) / float64(len(idx.docs))
+	length := float64(idx.docs[id].length)
+	return idx.idf(term) * freq * (k1 + 1) / (freq + k1*(1-b+b*length/avgLength))
+}
+
+// hasPhrase reports whether the terms appear consecutively in document id.
+func (idx *Index) hasPhrase(terms []string, id int) bool {
+	if len(terms) == 0 {
+		return true
+	}
+	for _, start := range idx.postings[terms[0]][id] {
+		matched := true
+		for offset, term := range terms[1:] {
+			if !containsInt(idx.postings[term][id], start+offset+1) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(sorted []int, value int) bool {
+	i := sort.SearchInts(sorted, value)
+	return i < len(sorted) && sorted[i] == value
+}
+
+type Result struct {
+	Issue *gcode.Issue `json:"issue"`
+	Score float64      `json:"score"`
+}
+
+// Search ranks issues by BM25 against every word of the query. Quoted phrases
+// must appear verbatim in an issue for it to match.
+func (idx *Index) Search(query string, limit int) []*Result {
+	q := ParseQuery(query)
+	if len(q.Terms) == 0 {
+		return nil
+	}
+
+	scores := make(map[int]float64)
+	for _, term := range q.Terms {
+		for id := range idx.postings[term] {
+			scores[id] += idx.bm25(term, id)
+		}
+	}
+
+	results := make([]*Result, 0, len(scores))
+	for id, score := range scores {
+		matched := true
+		for _, phrase := range q.Phrases {
+			if !idx.hasPhrase(phrase, id) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			results = append(results, &Result{Issue: idx.docs[id].issue, Score: score})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Issue.ID < results[j].Issue.ID
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+type Query struct {
+	Terms   []string
+	Phrases [][]string
+}
+
+func ParseQuery(query string) *Query {
+	q := &Query{}
+	parts := strings.Split(query, "\"")
+	for i, part := range parts {
+		tokens := Tokenize(part)
+		// Odd parts are inside quotes
+		if i%2 == 1 && len(tokens) > 1 {
+			q.Phrases = append(q.Phrases, tokens)
+		}
+		q.Terms = append(q.Terms, tokens...)
+	}
+	return q
+}
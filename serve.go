@@ -0,0 +1,106 @@
+package main
+
+import (
+	"expvar"
+	"flag"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tbuckley/go-issuetracker/alerts"
+	"github.com/tbuckley/go-issuetracker/analytics"
+	"github.com/tbuckley/go-issuetracker/metrics"
+	"github.com/tbuckley/go-issuetracker/query"
+)
+
+// metricsServer keeps the metrics computed after the last sync.
+type metricsServer struct {
+	wg       *query.WorkGroup
+	lock     sync.RWMutex
+	status   metrics.SyncStatus
+	families []*metrics.Family
+
+	// Alerts are evaluated after every successful sync if configured
+	alerts     *alerts.Config
+	alertState *alerts.State
+	notifiers  []alerts.Notifier
+	milestone  int
+}
+
+func (s *metricsServer) sync(q *query.Query) {
+	start := time.Now()
+	issues, err := query.CollectIssues(q.FetchAllIssues())
+
+	s.lock.Lock()
+	if err != nil {
+		log.Printf("Error syncing issues: %v", err)
+		s.status.Failure(start, time.Now())
+		s.lock.Unlock()
+		return
+	}
+	s.status.Success(start, time.Now(), len(issues))
+	s.families = metrics.IssueFamilies(issues)
+	log.Printf("Synced %v issues in %v", len(issues), s.status.Duration)
+	s.lock.Unlock()
+
+	// Notifiers can be slow, so alerts are sent without blocking scrapes.
+	// Only sync touches the alert state, and syncs don't overlap.
+	if s.alerts != nil {
+		events := alerts.Evaluate(s.alerts.Rules, s.alertState, time.Now(), analytics.HealthMetrics(issues, s.milestone))
+		for _, event := range events {
+			log.Printf("Alert: %v", event.String())
+		}
+		if err := alerts.NotifyAll(s.notifiers, events); err != nil {
+			log.Printf("Error sending alerts: %v", err)
+		}
+	}
+}
+
+func (s *metricsServer) collect(r *http.Request) ([]*metrics.Family, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	families := append([]*metrics.Family(nil), s.families...)
+	families = append(families, s.status.Families()...)
+	return append(families, metrics.WorkGroupFamilies(s.wg.Stats())...), nil
+}
+
+func runServe(wg *query.WorkGroup, client *http.Client, args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", ":9090", "Address to serve /metrics on")
+	project := flags.String("project", "chromium", "Project to sync")
+	q := flags.String("query", "Cr:UI", "Search query selecting the issues")
+	interval := flags.Duration("interval", time.Hour, "Time between syncs")
+	alertsFile := flags.String("alerts", "", "Alert rules and notifiers to evaluate after each sync, e.g. gae/alerts.json")
+	milestone := flags.Int("milestone", 42, "Current milestone, for alerts")
+	flags.Parse(args)
+
+	fetch := wg.NewQuery(*project).Client(client).Query(*q).Priority(query.Background)
+	server := &metricsServer{wg: wg, milestone: *milestone}
+	if *alertsFile != "" {
+		data, err := ioutil.ReadFile(*alertsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		server.alerts, err = alerts.ParseConfig(data)
+		if err != nil {
+			log.Fatal(err)
+		}
+		server.alertState = alerts.NewState()
+		server.notifiers = server.alerts.Notifiers(nil)
+	}
+	go func() {
+		for {
+			server.sync(fetch)
+			time.Sleep(*interval)
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler(server.collect))
+	wg.PublishExpvar("workgroup")
+	mux.Handle("/debug/vars", expvar.Handler())
+	log.Printf("Serving metrics on %v/metrics", *addr)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/tbuckley/go-issuetracker/gcode"
+	"github.com/tbuckley/go-issuetracker/graph"
+	"github.com/tbuckley/go-issuetracker/query"
+)
+
+func runShow(wg *query.WorkGroup, client *http.Client, args []string) {
+	flags := flag.NewFlagSet("show", flag.ExitOnError)
+	project := flags.String("project", "chromium", "Project of the issues")
+	replies := flags.Bool("replies", false, "Show the replies of each issue")
+	blockers := flags.Bool("blockers", false, "Show the open blockers of each issue")
+	rounds := flags.Int("rounds", 5, "Maximum rounds of fetching blockers")
+	flags.Parse(args)
+
+	ids := make([]int, 0, flags.NArg())
+	for _, arg := range flags.Args() {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			fmt.Printf("Invalid issue ID: %v\n", arg)
+			return
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		fmt.Println("Usage: ./go-issuetracker show [flags] ID...")
+		return
+	}
+
+	fetch := wg.NewIssues(*project).Client(client)
+	if *replies {
+		fetch = fetch.Replies()
+	}
+	issues, err := fetch.GetAll(ids)
+	var notFound *query.NotFoundError
+	if errors.As(err, &notFound) {
+		fmt.Printf("Not found: %v\n", notFound.IDs)
+	} else if err != nil {
+		fmt.Printf("Error: %v\n", err.Error())
+		return
+	}
+
+	var g *graph.Graph
+	if *blockers {
+		g = graph.New(issues)
+		err = g.Resolve(graph.QueryFetcher(wg.NewIssues(*project).Client(client)), *rounds)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err.Error())
+			return
+		}
+	}
+
+	for _, issue := range issues {
+		showIssue(issue)
+		if g != nil {
+			fmt.Println("Open blockers:")
+			for _, id := range g.OpenBlockers(issue.ID) {
+				fmt.Printf("  crbug.com/%v %v\n", id, g.Nodes[id].Title())
+			}
+			fmt.Printf("Critical path: %v\n", g.CriticalPath(issue.ID))
+		}
+		fmt.Println()
+	}
+}
+
+func showIssue(issue *gcode.Issue) {
+	fmt.Printf("crbug.com/%v: %v\n", issue.ID, issue.Title)
+	fmt.Printf("State: %v (%v)\n", issue.State, issue.Status)
+	fmt.Printf("Owner: %v\n", issue.Owner)
+	fmt.Printf("Reporter: %v\n", issue.Author)
+	fmt.Printf("Labels: %v\n", strings.Join(issue.Labels, ", "))
+	fmt.Printf("Published: %v\n", issue.Published)
+	fmt.Printf("Updated: %v\n", issue.Updated)
+	if len(issue.BlockedOn) > 0 {
+		fmt.Printf("Blocked on: %v\n", strings.Join(issue.BlockedOn, ", "))
+	}
+	if len(issue.Blocking) > 0 {
+		fmt.Printf("Blocking: %v\n", strings.Join(issue.Blocking, ", "))
+	}
+	fmt.Println()
+	fmt.Println(issue.Content)
+	for i, reply := range issue.Replies {
+		fmt.Printf("\n--- Comment %v by %v on %v ---\n", i+1, reply.Author, reply.Published)
+		fmt.Println(reply.Content)
+	}
+}